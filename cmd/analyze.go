@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"lem-in/logic"
+)
+
+// defaultAnalyzeAnts — верхняя граница диапазона муравьёв по умолчанию.
+const defaultAnalyzeAnts = 30
+
+// runAnalyze печатает сводку о пропускной способности карты:
+// "lem-in analyze [--ants FROM-TO] [--json] map.txt".
+func runAnalyze(args []string) {
+	fs := flag.NewFlagSet("lem-in analyze", flag.ExitOnError)
+	antsRange := fs.String("ants", "", fmt.Sprintf("ant counts to predict, FROM-TO (default 1 to min(map ants, %d))", defaultAnalyzeAnts))
+	rulesName := fs.String("rules", "vertex", "movement rules: vertex, no-swap or edge")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lem-in analyze [flags] <input_file>")
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+	if len(args) < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	rules, err := logic.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: cannot read file %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	g, err := logic.Parse(string(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid data format: %v\n", err)
+		os.Exit(1)
+	}
+	from, to := 1, min(g.NumAnts, defaultAnalyzeAnts)
+	if *antsRange != "" {
+		if from, to, err = parseRange(*antsRange); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	a, err := logic.Analyze(context.Background(), g, rules, from, to)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(a)
+		return
+	}
+	printAnalysis(a)
+}
+
+func printAnalysis(a *logic.Analysis) {
+	fmt.Printf("rooms: %d, tunnels: %d\n", a.Rooms, a.Tunnels)
+	if a.Unbounded {
+		fmt.Println("max flow: not limited by rooms (start and end are linked directly)")
+	} else {
+		fmt.Printf("max flow (vertex-disjoint paths): %d\n", a.MaxFlow)
+		fmt.Printf("min vertex cut: %s\n", strings.Join(a.MinCut, ", "))
+	}
+	if a.ShortestPath < 0 {
+		fmt.Println("shortest path: none (no end room is reachable)")
+	} else {
+		fmt.Printf("shortest path: %d turns\n", a.ShortestPath)
+	}
+	if a.DiameterExact {
+		fmt.Printf("diameter of the reachable subgraph: %d\n", a.Diameter)
+	} else {
+		fmt.Printf("diameter of the reachable subgraph: ≥ %d (estimated on a large map)\n", a.Diameter)
+	}
+	if len(a.Predictions) == 0 {
+		return
+	}
+	fmt.Printf("\n%6s %13s %12s %6s\n", "ants", "solver turns", "lower bound", "paths")
+	for _, p := range a.Predictions {
+		fmt.Printf("%6d %13d %12d %6d\n", p.Ants, p.SolverTurns, p.LowerBound, p.Paths)
+	}
+}
+
+// parseRange разбирает диапазон "FROM-TO" или одно число.
+func parseRange(text string) (int, int, error) {
+	lo, hi, found := strings.Cut(text, "-")
+	if !found {
+		hi = lo
+	}
+	from, err1 := strconv.Atoi(strings.TrimSpace(lo))
+	to, err2 := strconv.Atoi(strings.TrimSpace(hi))
+	if err1 != nil || err2 != nil || from < 1 || to < from {
+		return 0, 0, fmt.Errorf("invalid ant range %q (want FROM-TO with 1 <= FROM <= TO)", text)
+	}
+	return from, to, nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"lem-in/logic"
+)
+
+// batchResult — итог решения одной карты в "lem-in batch".
+type batchResult struct {
+	File       string  `json:"file"`
+	Rooms      int     `json:"rooms"`
+	Links      int     `json:"links"`
+	Ants       int     `json:"ants"`
+	Paths      int     `json:"paths"`       // путей, по которым прошёл хотя бы один муравей
+	Turns      int     `json:"turns"`       // -1, если решения нет
+	LowerBound int     `json:"lower_bound"` // -1, если финиш недостижим
+	RuntimeMS  float64 `json:"runtime_ms"`
+	Status     string  `json:"status"` // "ok" или "ERROR: причина"
+
+	runtime time.Duration
+}
+
+// runBatch решает все карты каталога параллельно и печатает сводную
+// таблицу: "lem-in batch [--jobs N] [--format table|csv|json] dir".
+// Код выхода 1, если хотя бы одна карта не решена.
+func runBatch(args []string) {
+	fs := flag.NewFlagSet("lem-in batch", flag.ExitOnError)
+	jobs := fs.Int("jobs", runtime.NumCPU(), "number of maps solved concurrently")
+	format := fs.String("format", "table", "output format: table, csv or json")
+	rulesName := fs.String("rules", "vertex", "movement rules: vertex, no-swap or edge")
+	exact := fs.Bool("exact", false, "use the exact (slow) time-expanded flow solver")
+	validate := fs.Bool("validate", false, "check every solution against the rules")
+	timeout := fs.Duration("timeout", 0, "time limit per map (0 — no limit)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lem-in batch [flags] <dir>")
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+	if len(args) < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	if *format != "table" && *format != "csv" && *format != "json" {
+		fmt.Fprintf(os.Stderr, "ERROR: unknown format %q (want table, csv or json)\n", *format)
+		os.Exit(1)
+	}
+	rules, err := logic.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	files, err := mapFiles(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: cannot read directory %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+
+	opts := logic.Options{Exact: *exact, Rules: rules}
+	results := make([]batchResult, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range max(*jobs, 1) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				results[i] = solveFile(files[i], opts, *validate, *timeout)
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	switch *format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(results)
+	case "csv":
+		writeBatchCSV(results)
+	default:
+		writeBatchTable(results)
+	}
+	for _, r := range results {
+		if r.Status != "ok" {
+			os.Exit(1)
+		}
+	}
+}
+
+// mapFiles возвращает обычные файлы каталога (кроме скрытых) по имени.
+func mapFiles(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, e := range entries {
+		if e.Type().IsRegular() && !strings.HasPrefix(e.Name(), ".") {
+			files = append(files, filepath.Join(dir, e.Name()))
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// solveFile читает, решает и при необходимости проверяет одну карту.
+// Время считается только для решателя.
+func solveFile(path string, opts logic.Options, validate bool, timeout time.Duration) batchResult {
+	r := batchResult{File: path, Turns: -1, LowerBound: -1}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		r.Status = "ERROR: cannot read file: " + err.Error()
+		return r
+	}
+	g, err := logic.Parse(string(data))
+	if err != nil {
+		r.Status = "ERROR: invalid data format: " + err.Error()
+		return r
+	}
+	r.Rooms, r.Ants = len(g.Rooms), g.NumAnts
+	if g.Source != nil {
+		r.Links = len(g.Source.Links)
+	}
+	r.LowerBound = logic.LowerBound(g)
+
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	start := time.Now()
+	result := logic.Solve(ctx, g, opts)
+	r.runtime = time.Since(start)
+	r.RuntimeMS = float64(r.runtime.Microseconds()) / 1000
+	if err := ctx.Err(); err != nil {
+		r.Status = "ERROR: solver stopped: " + err.Error()
+		return r
+	}
+	if result.Error != "" {
+		r.Status = result.Error
+		return r
+	}
+	r.Turns = len(result.Output)
+	r.Paths = len(result.Paths)
+	if validate {
+		if err := logic.Validate(string(data), result.Output, opts.Rules); err != nil {
+			r.Status = "ERROR: invalid solution: " + err.Error()
+			return r
+		}
+	}
+	r.Status = "ok"
+	return r
+}
+
+// batchColumns — заголовки таблицы и CSV.
+var batchColumns = []string{"file", "rooms", "links", "ants", "paths", "turns", "lower_bound", "runtime", "status"}
+
+// batchRow — ячейки строки; у карт, которые не разобрались или не решились,
+// неизвестные значения — "-".
+func batchRow(r batchResult, elapsed string) []string {
+	num := func(n int) string {
+		if n < 0 {
+			return "-"
+		}
+		return strconv.Itoa(n)
+	}
+	row := []string{r.File, "-", "-", "-", "-", num(r.Turns), num(r.LowerBound), elapsed, r.Status}
+	if r.Rooms > 0 {
+		row[1], row[2], row[3] = num(r.Rooms), num(r.Links), num(r.Ants)
+	}
+	if r.Turns >= 0 {
+		row[4] = num(r.Paths)
+	}
+	return row
+}
+
+func writeBatchTable(results []batchResult) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, strings.ToUpper(strings.Join(batchColumns, "\t")))
+	for _, r := range results {
+		fmt.Fprintln(tw, strings.Join(batchRow(r, r.runtime.Round(time.Microsecond).String()), "\t"))
+	}
+	tw.Flush()
+}
+
+func writeBatchCSV(results []batchResult) {
+	w := csv.NewWriter(os.Stdout)
+	header := append([]string{}, batchColumns...)
+	header[7] = "runtime_ms"
+	w.Write(header)
+	for _, r := range results {
+		w.Write(batchRow(r, strconv.FormatFloat(r.RuntimeMS, 'f', 3, 64)))
+	}
+	w.Flush()
+}
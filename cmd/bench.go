@@ -0,0 +1,192 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"lem-in/logic"
+)
+
+// benchSizes — размеры карт (число комнат) по умолчанию.
+var benchSizes = []int{100, 1000, 10000, 100000}
+
+// benchStages — замеряемые стадии в порядке выполнения решателем.
+// paths — выбор путей и распределение, simulate — симуляция по готовому
+// плану, solve — обе стадии вместе.
+var benchStages = []string{"parse", "lower-bound", "predict", "paths", "simulate", "exact", "solve"}
+
+// benchTime — сколько длится замер одной стадии.
+const benchTime = time.Second
+
+// runBench замеряет стадии движка на синтетических картах и печатает
+// ns/op и выделения памяти: "lem-in bench [--sizes 100,1000] [--stages parse,solve]".
+// Бенчмарки внутренних стадий для benchstat — "go test -bench . ./logic".
+func runBench(args []string) {
+	fs := flag.NewFlagSet("lem-in bench", flag.ExitOnError)
+	sizesList := fs.String("sizes", joinInts(benchSizes), "comma-separated map sizes in rooms")
+	stagesList := fs.String("stages", strings.Join(benchStages, ","), "comma-separated stages to measure")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lem-in bench [flags]")
+		fs.PrintDefaults()
+	}
+	parseArgs(fs, args)
+
+	var sizes []int
+	for _, s := range strings.Split(*sizesList, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || n < 2 {
+			fmt.Fprintf(os.Stderr, "ERROR: invalid map size %q\n", s)
+			os.Exit(1)
+		}
+		sizes = append(sizes, n)
+	}
+	var stages []string
+	for _, s := range strings.Split(*stagesList, ",") {
+		s = strings.TrimSpace(s)
+		if !slices.Contains(benchStages, s) {
+			fmt.Fprintf(os.Stderr, "ERROR: unknown stage %q (want %s)\n", s, strings.Join(benchStages, ", "))
+			os.Exit(1)
+		}
+		stages = append(stages, s)
+	}
+
+	// Строки печатаются по мере замеров (большие карты считаются долго),
+	// поэтому ширина столбцов фиксирована
+	const row = "%-15s %8s %8s %14s %14s %12s\n"
+	fmt.Printf(row, "STAGE", "ROOMS", "RUNS", "NS/OP", "B/OP", "ALLOCS/OP")
+	for _, n := range sizes {
+		text := logic.GenerateMap(n)
+		g, err := logic.Parse(text)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: generated map with %d rooms: %v\n", n, err)
+			os.Exit(1)
+		}
+		for _, stage := range stages {
+			run := benchStage(stage, text, g)
+			if run == nil {
+				continue
+			}
+			r, err := measure(run)
+			if err != nil {
+				fmt.Printf(row, stage, strconv.Itoa(n), "-", "-", "-", "failed")
+				fmt.Fprintf(os.Stderr, "ERROR: %s on %d rooms: %v\n", stage, n, err)
+				continue
+			}
+			fmt.Printf(row, stage, strconv.Itoa(n), strconv.Itoa(r.runs),
+				strconv.FormatInt(r.nsPerOp, 10), strconv.FormatUint(r.bytesPerOp, 10), strconv.FormatUint(r.allocsPerOp, 10))
+		}
+	}
+}
+
+// benchStage возвращает один прогон стадии на карте g (text — её текст)
+// или nil, если стадия к такой карте не применяется.
+func benchStage(stage, text string, g *logic.Graph) func() error {
+	ctx := context.Background()
+	solve := func(opts logic.Options) func() error {
+		return func() error {
+			if resp := logic.Solve(ctx, g, opts); resp.Error != "" {
+				return fmt.Errorf("%s", resp.Error)
+			}
+			return nil
+		}
+	}
+	switch stage {
+	case "parse":
+		return func() error {
+			_, err := logic.Parse(text)
+			return err
+		}
+	case "lower-bound":
+		return func() error {
+			if logic.LowerBound(g) < 0 {
+				return logic.ErrNoPaths
+			}
+			return nil
+		}
+	case "predict":
+		return func() error {
+			if turns, _ := logic.PredictTurns(g); turns < 0 {
+				return logic.ErrNoPaths
+			}
+			return nil
+		}
+	case "paths":
+		return func() error {
+			_, err := logic.NewPlan(ctx, g, logic.Options{})
+			return err
+		}
+	case "simulate":
+		p, err := logic.NewPlan(ctx, g, logic.Options{})
+		if err != nil {
+			return func() error { return err }
+		}
+		return func() error {
+			if resp := p.Run(ctx); resp.Error != "" {
+				return fmt.Errorf("%s", resp.Error)
+			}
+			return nil
+		}
+	case "exact":
+		// Сеть точного решателя растёт как комнаты × ходы; карты сверх
+		// её предела (logic.ErrExactTooLarge) пропускаются
+		if logic.CheckExact(ctx, g, logic.Options{}) != nil {
+			return nil
+		}
+		return solve(logic.Options{Exact: true})
+	case "solve":
+		return solve(logic.Options{})
+	}
+	return nil
+}
+
+// benchResult — итог замера одной стадии.
+type benchResult struct {
+	runs        int
+	nsPerOp     int64
+	bytesPerOp  uint64
+	allocsPerOp uint64
+}
+
+// measure запускает run сериями, удваивая их длину, пока серия
+// не займёт benchTime; результат — по последней серии.
+func measure(run func() error) (benchResult, error) {
+	if err := run(); err != nil {
+		return benchResult{}, err
+	}
+	for n := 1; ; n *= 2 {
+		runtime.GC()
+		var before, after runtime.MemStats
+		runtime.ReadMemStats(&before)
+		start := time.Now()
+		for i := 0; i < n; i++ {
+			if err := run(); err != nil {
+				return benchResult{}, err
+			}
+		}
+		elapsed := time.Since(start)
+		runtime.ReadMemStats(&after)
+		if elapsed >= benchTime || n >= 1<<30 {
+			return benchResult{
+				runs:        n,
+				nsPerOp:     elapsed.Nanoseconds() / int64(n),
+				bytesPerOp:  (after.TotalAlloc - before.TotalAlloc) / uint64(n),
+				allocsPerOp: (after.Mallocs - before.Mallocs) / uint64(n),
+			}, nil
+		}
+	}
+}
+
+func joinInts(values []int) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, ",")
+}
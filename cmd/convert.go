@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"lem-in/logic"
+)
+
+// runConvert переводит карту между текстовым форматом и JSON:
+// "lem-in convert [--to json|text] [--from auto|text|json] [-o FILE] map".
+// Результат записывается, только если он разбирается в ту же карту.
+func runConvert(args []string) {
+	fs := flag.NewFlagSet("lem-in convert", flag.ExitOnError)
+	to := fs.String("to", "", "output format: json or text (default: the other one)")
+	from := fs.String("from", "auto", "input format: auto (by extension), text or json")
+	outPath := fs.String("o", "", "write the result to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lem-in convert [--to json|text] [--from auto|text|json] [-o FILE] <input_file>")
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+	if len(args) < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	inFormat, err := mapFormat(*from, args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	outFormat := *to
+	if outFormat == "" {
+		outFormat = "json"
+		if inFormat == "json" {
+			outFormat = "text"
+		}
+	}
+	if outFormat != "json" && outFormat != "text" {
+		fmt.Fprintf(os.Stderr, "ERROR: unknown output format %q (want json or text)\n", outFormat)
+		os.Exit(1)
+	}
+
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: cannot read file %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	g, err := parseMap(data, inFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid data format: %v\n", err)
+		os.Exit(1)
+	}
+	out, err := formatMap(g, outFormat)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	// В тексте меток нет: они теряются, и сравнивать их не нужно
+	if outFormat == "text" && dropTags(g) {
+		fmt.Fprintln(os.Stderr, "warning: room tags cannot be written in text format and are dropped")
+	}
+	if check, err := parseMap(out, outFormat); err != nil || !logic.Equivalent(g, check) {
+		fmt.Fprintln(os.Stderr, "ERROR: converted map does not round-trip; nothing written")
+		os.Exit(1)
+	}
+
+	if *outPath == "" {
+		os.Stdout.Write(out)
+		return
+	}
+	if err := os.WriteFile(*outPath, out, 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// mapFormat разбирает название формата карты; "auto" выбирает JSON
+// для файлов с расширением .json, текст — для остальных.
+func mapFormat(name, path string) (string, error) {
+	switch name {
+	case "text", "json":
+		return name, nil
+	case "", "auto":
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			return "json", nil
+		}
+		return "text", nil
+	}
+	return "", fmt.Errorf("unknown input format %q (want auto, text or json)", name)
+}
+
+// parseMap разбирает карту в формате format ("text" или "json").
+func parseMap(data []byte, format string) (*logic.Graph, error) {
+	if format == "json" {
+		return logic.ParseJSON(data)
+	}
+	return logic.Parse(string(data))
+}
+
+// formatMap записывает карту в формате format ("text" или "json").
+func formatMap(g *logic.Graph, format string) ([]byte, error) {
+	if format == "json" {
+		return logic.FormatJSON(g)
+	}
+	return []byte(strings.Join(logic.Format(g), "\n") + "\n"), nil
+}
+
+// readMap читает карту path и возвращает её текст и граф. JSON-карта
+// решается как есть, а её текст нужен для печати и проверки ходов,
+// поэтому он должен разбираться в ту же карту (имя комнаты "b:2"
+// в тексте читается как вес туннеля). g == nil — текстовая карта
+// не разбирается; об этом сообщает вызывающий.
+func readMap(path, format string) (string, *logic.Graph, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, fmt.Errorf("cannot read file %s: %v", path, err)
+	}
+	if format, err = mapFormat(format, path); err != nil {
+		return "", nil, err
+	}
+	if format == "text" {
+		g, _ := logic.Parse(string(data))
+		return string(data), g, nil
+	}
+	g, err := logic.ParseJSON(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid data format: %v", err)
+	}
+	text := strings.Join(g.Lines(), "\n")
+	if check, err := logic.Parse(text); err != nil || !logic.Equivalent(g, check) {
+		return "", nil, fmt.Errorf("map %s cannot be written in text format unchanged; check room names containing ':'", path)
+	}
+	return text, g, nil
+}
+
+// dropTags убирает метки комнат g и сообщает, были ли они.
+func dropTags(g *logic.Graph) bool {
+	dropped := false
+	for _, r := range g.Rooms {
+		if len(r.Tags) > 0 {
+			r.Tags, dropped = nil, true
+		}
+	}
+	return dropped
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"lem-in/logic"
+)
+
+// runCountOnly печатает только число ходов и выбранные пути карты g,
+// не выполняя симуляцию. С crossCheck симуляция всё же выполняется,
+// и расхождение с прогнозом считается ошибкой. Прогноз и симуляция выполняются
+// с одними и теми же опциями opts.
+func runCountOnly(g *logic.Graph, opts logic.Options, crossCheck bool) {
+	turns, paths := logic.PredictTurnsWithOptions(g, opts)
+	if turns < 0 {
+		fmt.Println("ERROR: no valid paths found")
+		os.Exit(1)
+	}
+	if crossCheck {
+		result := logic.Solve(context.Background(), g, opts)
+		if result.Error != "" {
+			fmt.Fprintf(os.Stderr, "ERROR: cross-check: simulation failed: %s\n", strings.TrimPrefix(result.Error, "ERROR: "))
+			os.Exit(1)
+		}
+		if len(result.Output) != turns {
+			fmt.Fprintf(os.Stderr, "ERROR: cross-check: predicted %d turns, simulation took %d\n", turns, len(result.Output))
+			os.Exit(1)
+		}
+	}
+	fmt.Printf("turns: %d\n", turns)
+	for _, path := range paths {
+		fmt.Printf("path: %s\n", strings.Join(path, "-"))
+	}
+}
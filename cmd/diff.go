@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells ограничивает таблицу НОП (4 байта на клетку, до 16 МБ):
+// если после отбрасывания общих начала и конца изменённая середина
+// больше, она печатается одним блоком «всё удалено — всё добавлено».
+const maxDiffCells = 4_000_000
+
+// unifiedDiff возвращает разницу текстов в формате diff -u
+// (три строки контекста) или пустую строку, если тексты совпадают.
+func unifiedDiff(name, before, after string) string {
+	if before == after {
+		return ""
+	}
+	a, b := splitLines(before), splitLines(after)
+	ops := diffOps(a, b)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n+++ %s (formatted)\n", name, name)
+	const context = 3
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+		// Блок: изменения, между которыми не больше 2*context общих строк
+		start := max(i-context, 0)
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			run := end
+			for run < len(ops) && ops[run].kind == ' ' {
+				run++
+			}
+			if run == len(ops) || run-end > 2*context {
+				end = min(end+context, len(ops))
+				break
+			}
+			end = run
+		}
+		aStart, bStart, aLen, bLen := ops[start].a, ops[start].b, 0, 0
+		for _, op := range ops[start:end] {
+			if op.kind != '+' {
+				aLen++
+			}
+			if op.kind != '-' {
+				bLen++
+			}
+		}
+		fmt.Fprintf(&out, "@@ -%d,%d +%d,%d @@\n", aStart+1, aLen, bStart+1, bLen)
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&out, "%c%s\n", op.kind, op.text)
+		}
+		i = end
+	}
+	return out.String()
+}
+
+// diffOp — строка результата: ' ' общая, '-' удалена, '+' добавлена;
+// a и b — номера строк (с нуля) в исходном и новом тексте.
+type diffOp struct {
+	kind byte
+	text string
+	a, b int
+}
+
+// diffOps строит редакционное предписание по наибольшей общей
+// подпоследовательности строк. Общие начало и конец текстов совпадают
+// построчно, поэтому таблица НОП строится только для середины.
+func diffOps(a, b []string) []diffOp {
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	var ops []diffOp
+	for i := range pre {
+		ops = append(ops, diffOp{' ', a[i], i, i})
+	}
+	ops = append(ops, lcsOps(a[pre:len(a)-suf], b[pre:len(b)-suf], pre, pre)...)
+	for k := suf; k > 0; k-- {
+		ops = append(ops, diffOp{' ', a[len(a)-k], len(a) - k, len(b) - k})
+	}
+	return ops
+}
+
+// lcsOps строит предписание для a и b по таблице НОП; offA и offB —
+// номера их первых строк в полных текстах.
+func lcsOps(a, b []string, offA, offB int) []diffOp {
+	var ops []diffOp
+	if len(a)*len(b) > maxDiffCells {
+		for i, line := range a {
+			ops = append(ops, diffOp{'-', line, offA + i, offB})
+		}
+		for j, line := range b {
+			ops = append(ops, diffOp{'+', line, offA + len(a), offB + j})
+		}
+		return ops
+	}
+	// lcs[i][j] — длина НОП суффиксов a[i:] и b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			ops = append(ops, diffOp{' ', a[i], offA + i, offB + j})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, diffOp{'-', a[i], offA + i, offB + j})
+			i++
+		default:
+			ops = append(ops, diffOp{'+', b[j], offA + i, offB + j})
+			j++
+		}
+	}
+	return ops
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimSuffix(text, "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// checkOps проверяет, что предписание переводит a в b и номера строк
+// в нём идут подряд.
+func checkOps(t *testing.T, a, b []string, ops []diffOp) {
+	t.Helper()
+	var gotA, gotB []string
+	for _, op := range ops {
+		if op.a != len(gotA) || op.b != len(gotB) {
+			t.Fatalf("op %c %q at %d,%d, want %d,%d", op.kind, op.text, op.a, op.b, len(gotA), len(gotB))
+		}
+		if op.kind != '+' {
+			gotA = append(gotA, op.text)
+		}
+		if op.kind != '-' {
+			gotB = append(gotB, op.text)
+		}
+	}
+	if !slices.Equal(gotA, a) || !slices.Equal(gotB, b) {
+		t.Fatalf("ops rebuild\n%v\n%v\nwant\n%v\n%v", gotA, gotB, a, b)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	before := "3\n##start\ns 0 0\n##end\ne 2 0\nm 1 0\ns-m\nm-e\n"
+	after := "3\n##start\ns 0 0\n##end\ne 2 0\nm 1 0\nm-e\ns-m\n"
+	want := `--- map.txt
++++ map.txt (formatted)
+@@ -4,5 +4,5 @@
+ ##end
+ e 2 0
+ m 1 0
+-s-m
+ m-e
++s-m
+`
+	if got := unifiedDiff("map.txt", before, after); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+	if got := unifiedDiff("map.txt", before, before); got != "" {
+		t.Errorf("equal texts give a diff:\n%s", got)
+	}
+	checkOps(t, splitLines(before), splitLines(after), diffOps(splitLines(before), splitLines(after)))
+}
+
+// TestDiffFallback — изменённая середина больше maxDiffCells: она
+// заменяется целиком, а общие начало и конец остаются контекстом.
+func TestDiffFallback(t *testing.T) {
+	n := 2100 // n*n > maxDiffCells
+	var a, b []string
+	a = append(a, "head")
+	b = append(b, "head")
+	for i := range n {
+		a = append(a, fmt.Sprintf("a%d", i))
+		b = append(b, fmt.Sprintf("b%d", i))
+	}
+	a = append(a, "tail")
+	b = append(b, "tail")
+	ops := diffOps(a, b)
+	checkOps(t, a, b, ops)
+	var kinds strings.Builder
+	for _, op := range ops {
+		if kinds.Len() == 0 || kinds.String()[kinds.Len()-1] != op.kind {
+			kinds.WriteByte(op.kind)
+		}
+	}
+	if kinds.String() != " -+ " {
+		t.Errorf("op runs %q, want %q", kinds.String(), " -+ ")
+	}
+	diff := unifiedDiff("big.txt", strings.Join(a, "\n"), strings.Join(b, "\n"))
+	if header := fmt.Sprintf("@@ -1,%d +1,%d @@", n+2, n+2); !strings.Contains(diff, header) {
+		t.Errorf("diff lacks %q:\n%s", header, diff[:min(200, len(diff))])
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"lem-in/logic"
+)
+
+// runFmt приводит карты к канонической записи: "lem-in fmt [-w|-d] map.txt...".
+// Без флагов результат печатается, -w перезаписывает файлы, -d печатает
+// разницу. Код выхода 1, если хотя бы один файл не удалось обработать.
+func runFmt(args []string) {
+	fs := flag.NewFlagSet("lem-in fmt", flag.ExitOnError)
+	write := fs.Bool("w", false, "write the result back to the file instead of printing it")
+	showDiff := fs.Bool("d", false, "print a diff instead of the formatted map")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lem-in fmt [-w] [-d] <input_file>...")
+		fs.PrintDefaults()
+	}
+	files := parseArgs(fs, args)
+	if len(files) < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	failed := false
+	for _, path := range files {
+		if err := formatFile(path, *write, *showDiff); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %s: %v\n", path, err)
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// formatFile форматирует один файл. Результат записывается, только если
+// он разбирается в тот же граф, что и исходный текст.
+func formatFile(path string, write, showDiff bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	g, err := logic.Parse(string(data))
+	if err != nil {
+		return fmt.Errorf("invalid data format: %v", err)
+	}
+	formatted := strings.Join(logic.Format(g), "\n") + "\n"
+	check, err := logic.Parse(formatted)
+	if err != nil || !logic.Equivalent(g, check) {
+		return fmt.Errorf("formatted map does not round-trip; file left unchanged")
+	}
+
+	if showDiff {
+		fmt.Print(unifiedDiff(path, string(data), formatted))
+	}
+	if write {
+		if formatted == string(data) {
+			return nil
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(path, []byte(formatted), info.Mode().Perm())
+	}
+	if !showDiff {
+		fmt.Print(formatted)
+	}
+	return nil
+}
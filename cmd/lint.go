@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"lem-in/logic"
+)
+
+// lintReport — JSON-вывод "lem-in lint --json".
+type lintReport struct {
+	File     string              `json:"file"`
+	Warnings []logic.LintWarning `json:"warnings"`
+}
+
+// runLint проверяет карту на подозрительные, но допустимые конструкции:
+// "lem-in lint [--json] map.txt". Код выхода 1, если есть замечания.
+func runLint(args []string) {
+	fs := flag.NewFlagSet("lem-in lint", flag.ExitOnError)
+	asJSON := fs.Bool("json", false, "print warnings as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lem-in lint [--json] <input_file>")
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+	if len(args) < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	path := args[0]
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: cannot read file %s: %v\n", path, err)
+		os.Exit(1)
+	}
+	g, err := logic.Parse(string(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid data format: %v\n", err)
+		os.Exit(1)
+	}
+
+	warnings := logic.Lint(g)
+	if *asJSON {
+		report := lintReport{File: path, Warnings: warnings}
+		if report.Warnings == nil {
+			report.Warnings = []logic.LintWarning{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		enc.Encode(report)
+	} else {
+		for _, w := range warnings {
+			fmt.Printf("%s:%d: %s: %s\n", path, w.Line, w.Check, w.Message)
+		}
+	}
+	if len(warnings) > 0 {
+		os.Exit(1)
+	}
+}
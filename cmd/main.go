@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
@@ -12,29 +14,144 @@ import (
 // Читает путь к файлу из аргументов, печатает исходный ввод
 // и результат симуляции (или ошибку) в требуемом формате.
 func main() {
-	if len(os.Args) <= 1 {
-		fmt.Fprintln(os.Stderr, "usage: lem-in <input_file>")
-		os.Exit(1)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "serve":
+			runServe(os.Args[2:])
+			return
+		case "repl":
+			runRepl(os.Args[2:])
+			return
+		case "lint":
+			runLint(os.Args[2:])
+			return
+		case "fmt":
+			runFmt(os.Args[2:])
+			return
+		case "analyze":
+			runAnalyze(os.Args[2:])
+			return
+		case "batch":
+			runBatch(os.Args[2:])
+			return
+		case "bench":
+			runBench(os.Args[2:])
+			return
+		case "reduce":
+			runReduce(os.Args[2:])
+			return
+		case "convert":
+			runConvert(os.Args[2:])
+			return
+		}
 	}
 
-	var input string
-	var inputLines []string
+	fs := flag.NewFlagSet("lem-in", flag.ExitOnError)
+	exact := fs.Bool("exact", false, "use the exact (slow) time-expanded flow solver")
+	rulesName := fs.String("rules", "vertex", "movement rules: vertex, no-swap or edge")
+	orderName := fs.String("order", "input", "tie-breaking between equal paths: input (declaration order) or name")
+	validate := fs.Bool("validate", false, "check the produced moves against the rules before printing (not with --events)")
+	schedulePath := fs.String("schedule", "", "write the per-ant schedule to this file (.json for JSON, CSV otherwise)")
+	explain := fs.Bool("explain", false, "print the solver's decision trace to stderr")
+	countOnly := fs.Bool("count-only", false, "print only the predicted turn count and paths, without simulating")
+	crossCheck := fs.Bool("cross-check", false, "with --count-only, also run the simulator and fail if the turn counts differ")
+	inputFormat := fs.String("input-format", "text", "map format: text, json or auto (by file extension)")
+	eventsPath := fs.String("events", "", "file with scenario events (\"turn N: close a-b\", \"turn N: close room X\")")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lem-in [flags] <input_file>")
+		fmt.Fprintln(os.Stderr, "       lem-in serve [flags]")
+		fmt.Fprintln(os.Stderr, "       lem-in repl [flags] <input_file>")
+		fmt.Fprintln(os.Stderr, "       lem-in lint [--json] <input_file>")
+		fmt.Fprintln(os.Stderr, "       lem-in fmt [-w] [-d] <input_file>...")
+		fmt.Fprintln(os.Stderr, "       lem-in analyze [flags] <input_file>")
+		fmt.Fprintln(os.Stderr, "       lem-in batch [flags] <dir>")
+		fmt.Fprintln(os.Stderr, "       lem-in bench [flags]")
+		fmt.Fprintln(os.Stderr, "       lem-in reduce --predicate P [flags] <input_file>")
+		fmt.Fprintln(os.Stderr, "       lem-in convert [--to json|text] [-o FILE] <input_file>")
+		fs.PrintDefaults()
+	}
+	args := parseArgs(fs, os.Args[1:])
+	rules, err := logic.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	order, err := logic.ParseOrder(*orderName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	if len(args) < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
 
-	filePath := os.Args[1]
-	data, err := os.ReadFile(filePath)
+	input, g, err := readMap(args[0], *inputFormat)
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "ERROR: cannot read file %s: %v\n", filePath, err)
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	if g == nil {
+		fmt.Println("ERROR: invalid data format")
+		os.Exit(1)
+	}
+	inputLines := strings.Split(strings.TrimSpace(input), "\n")
+
+	if *countOnly {
+		if *exact || *eventsPath != "" {
+			fmt.Fprintln(os.Stderr, "ERROR: --count-only cannot be combined with --exact or --events")
+			os.Exit(1)
+		}
+		runCountOnly(g, logic.Options{Rules: rules, Order: order}, *crossCheck)
+		return
+	}
+
+	// Validate проверяет ходы по исходной карте, а события её меняют
+	if *validate && *eventsPath != "" {
+		fmt.Fprintln(os.Stderr, "ERROR: --validate cannot be combined with --events")
 		os.Exit(1)
 	}
-	input = string(data)
-	inputLines = strings.Split(strings.TrimSpace(input), "\n")
 
 	// Run the simulation
-	result := logic.RunSimulation(input)
+	opts := logic.Options{Exact: *exact, Rules: rules, Order: order, Schedule: *schedulePath != "", Explain: *explain}
+	if *eventsPath != "" {
+		data, err := os.ReadFile(*eventsPath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: cannot read file %s: %v\n", *eventsPath, err)
+			os.Exit(1)
+		}
+		if opts.Events, err = logic.ParseEvents(string(data)); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	if opts.Schedule {
+		if err := logic.CheckSchedule(g, opts); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: --schedule: %v; use --exact for a schedule that matches the moves\n", err)
+			os.Exit(1)
+		}
+	}
+	result := logic.Solve(context.Background(), g, opts)
+	for _, line := range result.Explain {
+		fmt.Fprintln(os.Stderr, "# "+line)
+	}
 	if result.Error != "" {
 		fmt.Println(result.Error)
 		os.Exit(1)
 	}
+	if *validate {
+		if err := logic.Validate(input, result.Output, rules); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: invalid solution: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	if result.Schedule != nil {
+		if err := writeSchedule(*schedulePath, result.Schedule); err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: cannot write schedule %s: %v\n", *schedulePath, err)
+			os.Exit(1)
+		}
+	}
 
 	// Print input lines
 	for _, line := range inputLines {
@@ -42,8 +159,57 @@ func main() {
 	}
 	// Print blank line
 	fmt.Println()
-	// Print moves
-	for _, move := range result.Output {
+	// Print moves, with reroute points as comments before the turn they apply to
+	// (Output has a line for every turn, so line i is turn i+1)
+	next := 0
+	for i, move := range result.Output {
+		for ; next < len(result.Reroutes) && result.Reroutes[next].Turn <= i+1; next++ {
+			printReroute(result.Reroutes[next])
+		}
 		fmt.Println(move)
 	}
+	for ; next < len(result.Reroutes); next++ {
+		printReroute(result.Reroutes[next])
+	}
+}
+
+// writeSchedule сохраняет план муравьёв в JSON или CSV по расширению файла.
+func writeSchedule(path string, sched *logic.Schedule) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if strings.HasSuffix(path, ".json") {
+		err = sched.WriteJSON(f)
+	} else {
+		err = sched.WriteCSV(f)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// printReroute печатает точку перестроения пути строкой-комментарием.
+func printReroute(r logic.Reroute) {
+	who := r.Ant
+	if who == "" {
+		who = "waiting ants"
+	}
+	fmt.Printf("# turn %d: %s: %s -> %s\n", r.Turn, r.Event, who, strings.Join(r.Path, "-"))
+}
+
+// parseArgs разбирает флаги вперемешку с позиционными аргументами
+// (например, "lem-in map.txt --exact") и возвращает позиционные.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		fs.Parse(args)
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
 }
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+
+	"lem-in/logic"
+)
+
+// runReduce уменьшает карту, сохраняя сбой, заданный предикатом:
+// "lem-in reduce map.txt --predicate invalid-output|turns>N|turns>expected".
+// Прерывание (Ctrl-C) записывает наименьшую найденную к этому моменту карту.
+func runReduce(args []string) {
+	fs := flag.NewFlagSet("lem-in reduce", flag.ExitOnError)
+	spec := fs.String("predicate", "", "failure to preserve: invalid-output, turns>N or turns>expected")
+	expected := fs.Int("expected", 0, "expected turn count for turns>expected (default: the exact solver's result)")
+	rulesName := fs.String("rules", "vertex", "movement rules: vertex, no-swap or edge")
+	outPath := fs.String("o", "", "write the reduced map to this file instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lem-in reduce --predicate P [flags] <input_file>")
+		fmt.Fprintln(os.Stderr, "predicates:")
+		fmt.Fprintln(os.Stderr, "  invalid-output   the solver fails on a solvable map or its moves do not pass validation")
+		fmt.Fprintln(os.Stderr, "  turns>N          the solver takes more than N turns")
+		fmt.Fprintln(os.Stderr, "  turns>expected   the solver takes more turns than --expected or the exact solver")
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+	if len(args) < 1 || *spec == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+	rules, err := logic.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	fails, err := parsePredicate(ctx, *spec, rules, *expected)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: cannot read file %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	g, err := logic.Parse(string(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid data format: %v\n", err)
+		os.Exit(1)
+	}
+
+	checks := 0
+	reduced, err := logic.Reduce(ctx, g, func(c *logic.Graph) bool {
+		checks++
+		return fails(c)
+	})
+	if reduced == nil {
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		} else {
+			fmt.Fprintf(os.Stderr, "ERROR: predicate %s does not hold for %s\n", *spec, args[0])
+		}
+		os.Exit(1)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "interrupted (%v); writing the smallest map so far\n", err)
+	}
+	fmt.Fprintf(os.Stderr, "rooms %d -> %d, links %d -> %d, ants %d -> %d (%d checks)\n",
+		len(g.Rooms), len(reduced.Rooms), countLinks(g), countLinks(reduced), g.NumAnts, reduced.NumAnts, checks)
+
+	text := strings.Join(reduced.Lines(), "\n") + "\n"
+	if *outPath == "" {
+		fmt.Print(text)
+		return
+	}
+	if err := os.WriteFile(*outPath, []byte(text), 0o644); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// parsePredicate разбирает описание сбоя. Карта, на которой решения
+// нет вовсе, сбоем не считается: иначе уменьшение свелось бы
+// к разрыву всех путей. Такие карты отсеиваются дешёвой проверкой
+// достижимости до запуска решателя.
+func parsePredicate(ctx context.Context, spec string, rules logic.Rules, expected int) (func(*logic.Graph) bool, error) {
+	solve := func(g *logic.Graph, exact bool) (logic.Response, bool) {
+		if logic.LowerBound(g) < 0 {
+			return logic.Response{}, false
+		}
+		resp := logic.Solve(ctx, g, logic.Options{Rules: rules, Exact: exact})
+		return resp, ctx.Err() == nil && resp.Error != "ERROR: "+logic.ErrNoPaths.Error()
+	}
+	if spec == "invalid-output" {
+		return func(g *logic.Graph) bool {
+			resp, solvable := solve(g, false)
+			if !solvable {
+				return false
+			}
+			if resp.Error != "" {
+				return true
+			}
+			return logic.Validate(strings.Join(g.Lines(), "\n"), resp.Output, rules) != nil
+		}, nil
+	}
+	limit, ok := strings.CutPrefix(strings.ReplaceAll(spec, " ", ""), "turns>")
+	if !ok {
+		return nil, fmt.Errorf("unknown predicate %q (want invalid-output, turns>N or turns>expected)", spec)
+	}
+	if limit == "expected" {
+		return func(g *logic.Graph) bool {
+			resp, solvable := solve(g, false)
+			if !solvable || resp.Error != "" {
+				return false
+			}
+			want := expected
+			if want <= 0 {
+				exact, _ := solve(g, true)
+				if exact.Error != "" {
+					return false
+				}
+				want = len(exact.Output)
+			}
+			return len(resp.Output) > want
+		}, nil
+	}
+	n, err := strconv.Atoi(limit)
+	if err != nil {
+		return nil, fmt.Errorf("invalid turn limit in predicate %q", spec)
+	}
+	return func(g *logic.Graph) bool {
+		resp, solvable := solve(g, false)
+		return solvable && resp.Error == "" && len(resp.Output) > n
+	}, nil
+}
+
+// countLinks считает туннели; связи между одной парой комнат — один туннель.
+func countLinks(g *logic.Graph) int {
+	seen := make(map[[2]string]bool)
+	for from, links := range g.Links {
+		for _, to := range links {
+			seen[[2]string{min(from, to), max(from, to)}] = true
+		}
+	}
+	return len(seen)
+}
@@ -0,0 +1,207 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"lem-in/logic"
+)
+
+// runRepl запускает интерактивный редактор карты: "lem-in repl map.txt".
+func runRepl(args []string) {
+	fs := flag.NewFlagSet("lem-in repl", flag.ExitOnError)
+	exact := fs.Bool("exact", false, "use the exact (slow) time-expanded flow solver")
+	rulesName := fs.String("rules", "vertex", "movement rules: vertex, no-swap or edge")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lem-in repl [flags] <input_file>")
+		fs.PrintDefaults()
+	}
+	args = parseArgs(fs, args)
+	if len(args) < 1 {
+		fs.Usage()
+		os.Exit(1)
+	}
+	rules, err := logic.ParseRules(*rulesName)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+	data, err := os.ReadFile(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: cannot read file %s: %v\n", args[0], err)
+		os.Exit(1)
+	}
+	g, err := logic.Parse(string(data))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: invalid data format: %v\n", err)
+		os.Exit(1)
+	}
+
+	r := &repl{g: g, opts: logic.Options{Exact: *exact, Rules: rules}, out: os.Stdout}
+	r.solve()
+	r.run(os.Stdin)
+}
+
+// repl — состояние редактора: карта в памяти и её последнее решение.
+type repl struct {
+	g    *logic.Graph
+	opts logic.Options
+	out  io.Writer
+
+	result logic.Response
+	turns  int // число ходов последнего решения, -1 — решения нет
+	cursor int // сколько ходов уже показано командой step
+}
+
+const replHelp = `commands:
+  add room NAME X Y   add a room
+  link A B            add a tunnel A-B
+  unlink A B          remove all tunnels between A and B
+  ants N              set the number of ants
+  solve               print the moves of the current solution
+  paths               print the chosen paths and ants per path
+  step                print the next turn of the current solution
+  show                print the map
+  save FILE           write the map to FILE
+  help                show this help
+  quit                leave the editor`
+
+// run читает команды построчно до конца ввода или команды quit.
+func (r *repl) run(in io.Reader) {
+	sc := bufio.NewScanner(in)
+	for {
+		fmt.Fprint(r.out, "lem-in> ")
+		if !sc.Scan() {
+			fmt.Fprintln(r.out)
+			return
+		}
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if fields[0] == "quit" || fields[0] == "exit" {
+			return
+		}
+		if err := r.exec(fields); err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+		}
+	}
+}
+
+// exec выполняет одну команду. После правки карты решение пересчитывается.
+func (r *repl) exec(fields []string) error {
+	cmd, args := fields[0], fields[1:]
+	if cmd == "add" && len(args) > 0 && args[0] == "room" {
+		cmd, args = "add room", args[1:]
+	}
+	switch cmd {
+	case "add room":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: add room NAME X Y")
+		}
+		x, errX := strconv.Atoi(args[1])
+		y, errY := strconv.Atoi(args[2])
+		if errX != nil || errY != nil {
+			return fmt.Errorf("invalid coordinates %s %s", args[1], args[2])
+		}
+		return r.edit(r.g.AddRoom(args[0], x, y))
+	case "link", "unlink":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: %s A B", cmd)
+		}
+		if cmd == "link" {
+			return r.edit(r.g.AddLink(args[0], args[1]))
+		}
+		return r.edit(r.g.RemoveLink(args[0], args[1]))
+	case "ants":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: ants N")
+		}
+		n, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid number of ants: %s", args[0])
+		}
+		return r.edit(r.g.SetAnts(n))
+	case "solve":
+		if r.turns < 0 {
+			return fmt.Errorf("no solution: %s", strings.TrimPrefix(r.result.Error, "ERROR: "))
+		}
+		for _, line := range r.result.Output {
+			fmt.Fprintln(r.out, line)
+		}
+		fmt.Fprintf(r.out, "turns: %d\n", r.turns)
+	case "paths":
+		if r.turns < 0 {
+			return fmt.Errorf("no solution: %s", strings.TrimPrefix(r.result.Error, "ERROR: "))
+		}
+		for i, use := range r.result.Paths {
+			fmt.Fprintf(r.out, "%d: %s (%d rooms, %d ants)\n", i+1, strings.Join(use.Path, "-"), len(use.Path)-2, use.Ants)
+		}
+	case "step":
+		if r.turns < 0 {
+			return fmt.Errorf("no solution: %s", strings.TrimPrefix(r.result.Error, "ERROR: "))
+		}
+		if r.cursor >= len(r.result.Output) {
+			r.cursor = 0
+			fmt.Fprintln(r.out, "all ants have arrived; stepping restarts from turn 1")
+			return nil
+		}
+		r.cursor++
+		fmt.Fprintf(r.out, "turn %d: %s\n", r.cursor, r.result.Output[r.cursor-1])
+	case "show":
+		for _, line := range r.g.Lines() {
+			fmt.Fprintln(r.out, line)
+		}
+	case "save":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: save FILE")
+		}
+		text := strings.Join(r.g.Lines(), "\n") + "\n"
+		if err := os.WriteFile(args[0], []byte(text), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(r.out, "saved %s\n", args[0])
+	case "help":
+		fmt.Fprintln(r.out, replHelp)
+	default:
+		return fmt.Errorf("unknown command %q (try help)", cmd)
+	}
+	return nil
+}
+
+// edit завершает правку карты: при ошибке карта не менялась,
+// иначе решение пересчитывается и печатается изменение числа ходов.
+func (r *repl) edit(err error) error {
+	if err != nil {
+		return err
+	}
+	before := r.turns
+	r.solve()
+	switch {
+	case r.turns < 0:
+		fmt.Fprintf(r.out, "no solution: %s\n", strings.TrimPrefix(r.result.Error, "ERROR: "))
+	case before < 0:
+		fmt.Fprintf(r.out, "turns: %d (was unsolvable)\n", r.turns)
+	case r.turns == before:
+		fmt.Fprintf(r.out, "turns: %d (unchanged)\n", r.turns)
+	default:
+		fmt.Fprintf(r.out, "turns: %d -> %d (%+d)\n", before, r.turns, r.turns-before)
+	}
+	return nil
+}
+
+// solve решает текущую карту и сбрасывает пошаговый просмотр.
+func (r *repl) solve() {
+	r.result = logic.Solve(context.Background(), r.g, r.opts)
+	r.cursor = 0
+	r.turns = -1
+	if r.result.Error == "" {
+		r.turns = len(r.result.Output)
+	}
+}
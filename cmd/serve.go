@@ -0,0 +1,35 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"lem-in/server"
+)
+
+// runServe запускает HTTP JSON API: "lem-in serve --addr :8080".
+func runServe(args []string) {
+	fs := flag.NewFlagSet("lem-in serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "address to listen on")
+	timeout := fs.Duration("timeout", server.DefaultConfig.Timeout, "solver time limit per request")
+	maxBody := fs.Int64("max-body", server.DefaultConfig.MaxBodyBytes, "maximum request body size in bytes")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: lem-in serve [flags]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.NewHandler(server.Config{MaxBodyBytes: *maxBody, Timeout: *timeout}),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	fmt.Fprintf(os.Stderr, "lem-in: listening on %s\n", *addr)
+	if err := srv.ListenAndServe(); err != nil {
+		fmt.Fprintf(os.Stderr, "ERROR: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,102 @@
+//go:build js && wasm
+
+// Команда wasm — сборка движка для браузера:
+//
+//	GOOS=js GOARCH=wasm go build -o lemin.wasm ./cmd/wasm
+//
+// После запуска (через wasm_exec.js из поставки Go) в глобальной области
+// появляется объект lemin с функцией solve(mapText, options), которая
+// возвращает строку JSON с путями и ходами или с ошибкой.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"syscall/js"
+
+	"lem-in/logic"
+)
+
+// result — ответ lemin.solve.
+type result struct {
+	Turns int          `json:"turns"`
+	Paths []logic.Path `json:"paths,omitempty"`
+	Moves []string     `json:"moves,omitempty"`
+	Error *solveError  `json:"error,omitempty"`
+}
+
+// solveError — ошибка разбора или решения. Line — номер строки карты
+// (или файла событий, если Source == "events"), 0 — если строка неизвестна.
+type solveError struct {
+	Message string `json:"message"`
+	Source  string `json:"source"` // map, options, events или solver
+	Line    int    `json:"line,omitempty"`
+}
+
+func main() {
+	js.Global().Set("lemin", js.ValueOf(map[string]any{
+		"solve": js.FuncOf(func(this js.Value, args []js.Value) any {
+			var mapText string
+			options := js.Undefined()
+			if len(args) > 0 {
+				mapText = args[0].String()
+			}
+			if len(args) > 1 {
+				options = args[1]
+			}
+			data, _ := json.Marshal(solve(mapText, options))
+			return string(data)
+		}),
+	}))
+	// Функции остаются доступны, пока работает программа
+	select {}
+}
+
+// solve разбирает карту и опции ({rules, exact, events}) и решает карту.
+func solve(mapText string, options js.Value) result {
+	var opts logic.Options
+	if options.Type() == js.TypeObject {
+		if v := options.Get("rules"); v.Type() == js.TypeString {
+			rules, err := logic.ParseRules(v.String())
+			if err != nil {
+				return failure("options", err)
+			}
+			opts.Rules = rules
+		}
+		if v := options.Get("exact"); v.Type() == js.TypeBoolean {
+			opts.Exact = v.Bool()
+		}
+		if v := options.Get("events"); v.Type() == js.TypeString {
+			events, err := logic.ParseEvents(v.String())
+			if err != nil {
+				return failure("events", err)
+			}
+			opts.Events = events
+		}
+	}
+
+	g, err := logic.Parse(mapText)
+	if err != nil {
+		return failure("map", err)
+	}
+	resp := logic.Solve(context.Background(), g, opts)
+	if resp.Error != "" {
+		return result{Error: &solveError{Message: strings.TrimPrefix(resp.Error, "ERROR: "), Source: "solver"}}
+	}
+	paths := make([]logic.Path, len(resp.Paths))
+	for i, use := range resp.Paths {
+		paths[i] = use.Path
+	}
+	return result{Turns: len(resp.Output), Paths: paths, Moves: resp.Output}
+}
+
+func failure(source string, err error) result {
+	e := &solveError{Message: err.Error(), Source: source}
+	var parseErr *logic.ParseError
+	if errors.As(err, &parseErr) {
+		e.Line = parseErr.Line
+	}
+	return result{Error: e}
+}
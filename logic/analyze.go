@@ -0,0 +1,240 @@
+package logic
+
+import (
+	"context"
+	"sort"
+)
+
+// exactDiameterRooms — до скольких комнат диаметр считается точно
+// (обход в ширину из каждой комнаты); на больших картах — оценка снизу.
+const exactDiameterRooms = 2000
+
+// Analysis — сводка о пропускной способности карты.
+type Analysis struct {
+	Rooms   int `json:"rooms"`
+	Tunnels int `json:"tunnels"`
+	// MaxFlow — сколько муравьёв одновременно проходят по
+	// непересекающимся путям (с учётом вместимости комнат).
+	// Unbounded — старт и финиш связаны туннелем без промежуточных
+	// комнат, и комнаты поток не ограничивают.
+	MaxFlow   int      `json:"max_flow"`
+	Unbounded bool     `json:"unbounded,omitempty"`
+	MinCut    []string `json:"min_cut"` // комнаты минимального вершинного разреза
+	// ShortestPath — длина кратчайшего пути старт→финиш в ходах
+	// (туннель веса w — w ходов); -1, если финиш недостижим.
+	ShortestPath int `json:"shortest_path"`
+	// Diameter — наибольшее расстояние в ходах между комнатами,
+	// достижимыми со старта, без учёта направления туннелей.
+	// DiameterExact == false — оценка снизу для больших карт.
+	Diameter      int          `json:"diameter"`
+	DiameterExact bool         `json:"diameter_exact"`
+	Predictions   []Prediction `json:"predictions"`
+}
+
+// Prediction — прогноз для заданного числа муравьёв. SolverTurns —
+// сколько ходов займёт решение Solve; быстрый решатель эвристический,
+// поэтому это не обязательно оптимум. Оптимум лежит между LowerBound
+// и SolverTurns.
+type Prediction struct {
+	Ants        int `json:"ants"`
+	SolverTurns int `json:"solver_turns"`
+	LowerBound  int `json:"lower_bound"` // см. LowerBound
+	Paths       int `json:"paths"`       // путей, по которым идёт хотя бы один муравей
+}
+
+// Analyze строит сводку по карте g при правилах rules. Прогноз ходов
+// и путей считается тем же выбором путей и распределением, что и Solve,
+// для каждого числа муравьёв от minAnts до maxAnts; в режиме колоний
+// размеры колоний заданы картой, поэтому прогноз один — для g.NumAnts.
+func Analyze(ctx context.Context, g *Graph, rules Rules, minAnts, maxAnts int) (*Analysis, error) {
+	a := &Analysis{Rooms: len(g.Rooms), Tunnels: len(adjacencyDecls(g)), ShortestPath: -1}
+	// Взвешенный туннель — цепочка комнат вместимости 1, поэтому поток
+	// и разрез считаются на развёрнутом графе; в разрез могут попасть
+	// сегменты туннелей ("a-b:1").
+	ex, err := expandWeightedLinks(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	flow, cut, bounded := minVertexCut(ex)
+	a.MaxFlow, a.MinCut, a.Unbounded = flow, cut, !bounded
+	if !bounded {
+		a.MaxFlow = 0
+	}
+
+	for _, c := range ex.sources() {
+		if d := shortestDistance(ex, c.Start); d >= 0 && (a.ShortestPath < 0 || d < a.ShortestPath) {
+			a.ShortestPath = d
+		}
+	}
+	a.Diameter, a.DiameterExact = diameter(g, ex)
+
+	if len(g.Colonies) > 0 {
+		minAnts, maxAnts = g.NumAnts, g.NumAnts
+	}
+	if a.ShortestPath < 0 {
+		return a, nil
+	}
+	for n := minAnts; n <= maxAnts; n++ {
+		p, err := predict(ctx, g, rules, n)
+		if err != nil {
+			return nil, err
+		}
+		a.Predictions = append(a.Predictions, p)
+	}
+	return a, nil
+}
+
+// predict выбирает пути и распределяет n муравьёв так же, как Solve.
+func predict(ctx context.Context, g *Graph, rules Rules, n int) (Prediction, error) {
+	c := g.Clone()
+	if len(c.Colonies) == 0 {
+		c.NumAnts = n
+	}
+	sol, err := plan(ctx, c, Options{Rules: rules})
+	if err != nil {
+		return Prediction{}, err
+	}
+	turns, used := sol.predictedTurns()
+	c.Rules = rules
+	return Prediction{Ants: n, SolverTurns: turns, LowerBound: LowerBound(c), Paths: len(used)}, nil
+}
+
+// PredictTurns возвращает число ходов и пути, по которым пойдут муравьи,
+// не выполняя симуляцию: пути выбираются так же, как в Solve при правилах
+// g.Rules, а число ходов считает распределитель. Симуляция заканчивается
+// ровно на этом ходу. Если путей нет, возвращается -1. Граф g не изменяется.
+func PredictTurns(g *Graph) (int, []Path) {
+	return PredictTurnsWithOptions(g, Options{Rules: g.Rules})
+}
+
+// PredictTurnsWithOptions работает как PredictTurns, но выбирает пути
+// так же, как Solve с опциями opts (правила, порядок). Точный решатель
+// и события не учитываются.
+func PredictTurnsWithOptions(g *Graph, opts Options) (int, []Path) {
+	opts.Exact, opts.Events = false, nil
+	sol, err := plan(context.Background(), g, opts)
+	if err != nil {
+		return -1, nil
+	}
+	return sol.predictedTurns()
+}
+
+// LowerBound возвращает оценку снизу числа ходов для карты g при правилах
+// g.Rules и любых более строгих: через каждую комнату минимального
+// разреза за ход проходит не больше Capacity муравьёв, через туннель
+// старт→финиш — не больше directTunnelCapacity, а самый быстрый муравей
+// идёт по кратчайшему пути, поэтому ходов не меньше, чем
+// кратчайший путь + ⌈муравьи / поток за ход⌉ - 1.
+// Если финиш недостижим, возвращается -1.
+func LowerBound(g *Graph) int {
+	ex, err := expandWeightedLinks(context.Background(), g)
+	if err != nil {
+		return -1
+	}
+	shortest := -1
+	ants := 0
+	for _, c := range ex.sources() {
+		ants += c.Ants
+		if d := shortestDistance(ex, c.Start); d >= 0 && (shortest < 0 || d < shortest) {
+			shortest = d
+		}
+	}
+	if shortest < 0 || ants == 0 {
+		return min(shortest, 0)
+	}
+	flow := turnFlow(ex)
+	if flow == 0 {
+		return shortest
+	}
+	return shortest + (ants+flow-1)/flow - 1
+}
+
+// predictedTurns считает по распределению число ходов и пути, на которые
+// выходит хотя бы один муравей (во всех группах по порядку).
+func (sol *solution) predictedTurns() (int, []Path) {
+	turns := 0
+	var used []Path
+	for _, f := range sol.fleets {
+		counts, t := distributePaths(sol.g, f.paths, f.ants)
+		turns = max(turns, t)
+		for i, k := range counts {
+			if k > 0 {
+				used = append(used, f.paths[i])
+			}
+		}
+	}
+	return turns, used
+}
+
+// diameter считает диаметр части графа ex (с развёрнутыми туннелями),
+// достижимой со стартов, без учёта направлений; расстояния меряются
+// между настоящими комнатами g. На больших картах — двойной обход
+// (оценка снизу).
+func diameter(g, ex *Graph) (int, bool) {
+	// Достижимые со старта по направлению туннелей комнаты
+	reach := map[string]bool{}
+	queue := startNames(ex)
+	for _, name := range queue {
+		reach[name] = true
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range ex.Links[cur] {
+			if !reach[next] {
+				reach[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	nb := make(map[string][]string)
+	for from, links := range ex.Links {
+		for _, to := range links {
+			if reach[from] && reach[to] {
+				nb[from] = append(nb[from], to)
+				nb[to] = append(nb[to], from)
+			}
+		}
+	}
+	bfs := func(from string) (map[string]int, string) {
+		dist := map[string]int{from: 0}
+		far := from
+		queue := []string{from}
+		for len(queue) > 0 {
+			cur := queue[0]
+			queue = queue[1:]
+			if _, real := g.Rooms[cur]; real && dist[cur] > dist[far] {
+				far = cur
+			}
+			for _, next := range nb[cur] {
+				if _, seen := dist[next]; !seen {
+					dist[next] = dist[cur] + 1
+					queue = append(queue, next)
+				}
+			}
+		}
+		return dist, far
+	}
+
+	var rooms []string
+	for name := range reach {
+		if _, real := g.Rooms[name]; real {
+			rooms = append(rooms, name)
+		}
+	}
+	sort.Strings(rooms)
+
+	best := 0
+	if len(rooms) > exactDiameterRooms {
+		_, far := bfs(g.Start)
+		dist, other := bfs(far)
+		return dist[other], false
+	}
+	for _, from := range rooms {
+		dist, _ := bfs(from)
+		for _, to := range rooms {
+			best = max(best, dist[to])
+		}
+	}
+	return best, true
+}
@@ -0,0 +1,54 @@
+package logic
+
+import (
+	"context"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestLowerBound проверяет, что оценка снизу не превышает числа ходов
+// решателя на примерах, а на example02 с прямым туннелем старт→финиш
+// учитывает, что он пропускает одного муравья за ход.
+func TestLowerBound(t *testing.T) {
+	forEachExample(t, func(t *testing.T, path string, g *Graph) {
+		resp := Solve(context.Background(), g, Options{})
+		if resp.Error != "" {
+			t.Fatal(resp.Error)
+		}
+		bound := LowerBound(g)
+		if bound < 0 || bound > len(resp.Output) {
+			t.Errorf("lower bound %d, solver takes %d turns", bound, len(resp.Output))
+		}
+		if filepath.Base(path) == "example02.txt" && bound < 10 {
+			t.Errorf("lower bound %d ignores the direct tunnel capacity", bound)
+		}
+	})
+}
+
+// TestPredictTurns сверяет прогноз с симуляцией при тех же опциях.
+func TestPredictTurns(t *testing.T) {
+	forEachExample(t, func(t *testing.T, _ string, g *Graph) {
+		for _, opts := range []Options{{}, {Order: OrderName}, {Rules: RulesEdgeCapacity, Order: OrderName}} {
+			resp := Solve(context.Background(), g, opts)
+			turns, paths := PredictTurnsWithOptions(g, opts)
+			if resp.Error != "" || turns != len(resp.Output) {
+				t.Errorf("rules %s, order %s: predicted %d turns, simulation %d (%s)",
+					opts.Rules, opts.Order, turns, len(resp.Output), resp.Error)
+				continue
+			}
+			if len(paths) == 0 || len(resp.Paths) == 0 {
+				t.Errorf("order %s: %d predicted paths, solver uses %d", opts.Order, len(paths), len(resp.Paths))
+				continue
+			}
+			if !slices.Equal(paths[0], resp.Paths[0].Path) {
+				t.Errorf("order %s: first predicted path %v, solver uses %v", opts.Order, paths[0], resp.Paths[0].Path)
+			}
+		}
+		// PredictTurns берёт правила из карты
+		g.Rules = RulesEdgeCapacity
+		if got, _ := PredictTurns(g); got != len(Solve(context.Background(), g, Options{Rules: RulesEdgeCapacity}).Output) {
+			t.Errorf("PredictTurns ignores g.Rules, predicted %d turns", got)
+		}
+	})
+}
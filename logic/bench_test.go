@@ -0,0 +1,132 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// benchSizes — размеры сгенерированных карт (число комнат).
+var benchSizes = []int{100, 1000, 10000, 100000}
+
+// dfsSizes — карты, на которых dfsPaths заканчивает перебор: число
+// простых путей растёт экспоненциально (на 300 комнатах их сотни тысяч).
+var dfsSizes = []int{30, 100}
+
+// flowSizes — карты для потоков в развёрнутой сети: она растёт
+// как комнаты × ходы.
+var flowSizes = []int{30, 100}
+
+// benchMaps запускает bench под-бенчмарком на каждой карте из sizes.
+func benchMaps(b *testing.B, sizes []int, bench func(b *testing.B, text string, g *Graph)) {
+	for _, n := range sizes {
+		text := GenerateMap(n)
+		g, err := Parse(text)
+		if err != nil {
+			b.Fatalf("generated map with %d rooms: %v", n, err)
+		}
+		b.Run(fmt.Sprintf("rooms=%d", n), func(b *testing.B) { bench(b, text, g) })
+	}
+}
+
+func BenchmarkParse(b *testing.B) {
+	benchMaps(b, benchSizes, func(b *testing.B, text string, _ *Graph) {
+		for b.Loop() {
+			if _, err := Parse(text); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkPaths(b *testing.B) {
+	benchMaps(b, benchSizes, func(b *testing.B, _ string, g *Graph) {
+		for b.Loop() {
+			findDisjointPaths(g)
+		}
+	})
+}
+
+func BenchmarkDFSPaths(b *testing.B) {
+	benchMaps(b, dfsSizes, func(b *testing.B, _ string, g *Graph) {
+		for b.Loop() {
+			dfsPaths(context.Background(), g)
+		}
+	})
+}
+
+func BenchmarkTurnFlow(b *testing.B) {
+	benchMaps(b, benchSizes, func(b *testing.B, _ string, g *Graph) {
+		for b.Loop() {
+			turnFlow(g)
+		}
+	})
+}
+
+func BenchmarkExact(b *testing.B) {
+	benchMaps(b, flowSizes, func(b *testing.B, _ string, g *Graph) {
+		for b.Loop() {
+			if _, err := solveExact(context.Background(), g); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// benchTimeNet замеряет потоковый алгоритм flow на сети с оптимальным
+// горизонтом. Поток меняет остаточные ёмкости, поэтому сеть строится
+// в каждом прогоне и входит в замер.
+func benchTimeNet(b *testing.B, flow func(tn *timeNet, ants int) int) {
+	benchMaps(b, flowSizes, func(b *testing.B, _ string, g *Graph) {
+		ctx := context.Background()
+		tracks, err := solveExact(ctx, g)
+		if err != nil {
+			b.Fatal(err)
+		}
+		horizon := len(tracks[0].rooms) - 1
+		for b.Loop() {
+			tn, err := buildTimeNet(ctx, g, horizon)
+			if err != nil {
+				b.Fatal(err)
+			}
+			if flow(tn, g.NumAnts) < g.NumAnts {
+				b.Fatalf("flow below %d ants at horizon %d", g.NumAnts, horizon)
+			}
+		}
+	})
+}
+
+func BenchmarkMaxFlow(b *testing.B) {
+	benchTimeNet(b, func(tn *timeNet, ants int) int {
+		return tn.net.maxFlow(context.Background(), tn.source(), tn.sink(), ants)
+	})
+}
+
+func BenchmarkMinCostFlow(b *testing.B) {
+	benchTimeNet(b, func(tn *timeNet, ants int) int {
+		return tn.net.minCostFlow(context.Background(), tn.source(), tn.sink(), ants)
+	})
+}
+
+func BenchmarkDistribute(b *testing.B) {
+	benchMaps(b, benchSizes, func(b *testing.B, _ string, g *Graph) {
+		paths := findDisjointPaths(g)
+		for b.Loop() {
+			distributePaths(g, paths, g.NumAnts)
+		}
+	})
+}
+
+func BenchmarkSimulate(b *testing.B) {
+	benchMaps(b, benchSizes, func(b *testing.B, _ string, g *Graph) {
+		paths := findDisjointPaths(g)
+		counts, _ := distributePaths(g, paths, g.NumAnts)
+		for b.Loop() {
+			// Симуляция расходует счётчики, поэтому каждому прогону — своя копия
+			fleets := []fleet{{paths: paths, counts: append([]int(nil), counts...), ants: g.NumAnts}}
+			if _, _, err := moveAnts(context.Background(), g, fleets, nil); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,122 @@
+package logic
+
+import (
+	"fmt"
+)
+
+// sinkName — виртуальный общий финиш для поиска путей в режиме колоний.
+// Имена комнат не могут начинаться с '#', поэтому коллизий нет.
+const sinkName = "#sink"
+
+// planColonies выбирает пути для режима нескольких колоний и распределяет
+// по ним муравьёв. Задача решается как поток из нескольких истоков
+// в несколько стоков: все финиши сведены в виртуальный сток, а пути
+// добавляются жадно последовательными кратчайшими путями (как
+// в findDisjointPaths). На каждом шаге путь получает колония с наибольшим
+// текущим числом ходов, пока это число уменьшается. Пути разных колоний
+// не делят комнат сверх их вместимости. Пути каждой колонии нумеруются
+// согласно order.
+func planColonies(g *Graph, order Order) ([]fleet, error) {
+	paths := make([][]Path, len(g.Colonies))
+	done := make([]bool, len(g.Colonies))
+	used := make(map[string]int)
+	usedLinks := make(map[[2]string]bool)
+
+	turns := func(i int) int {
+		if len(paths[i]) == 0 {
+			return -1
+		}
+		return turnsFor(g, paths[i], g.Colonies[i].Ants)
+	}
+	// Колонии без путей идут первыми, затем — самая медленная
+	before := func(i, j int) bool {
+		ti, tj := turns(i), turns(j)
+		if ti < 0 || tj < 0 {
+			return ti < 0 && tj >= 0
+		}
+		return ti > tj
+	}
+
+	for {
+		pick := -1
+		for i := range g.Colonies {
+			if !done[i] && (pick < 0 || before(i, pick)) {
+				pick = i
+			}
+		}
+		if pick < 0 {
+			break
+		}
+
+		sg, left := colonySearchGraph(g, g.Colonies[pick].Start, used, usedLinks)
+		path, found := searchShortPath(sg, left)
+		if !found {
+			if len(paths[pick]) == 0 {
+				return nil, fmt.Errorf("no path from start %s to any end", g.Colonies[pick].Start)
+			}
+			done[pick] = true
+			continue
+		}
+		path = path[:len(path)-1] // без виртуального стока
+		if len(paths[pick]) > 0 {
+			candidate := append(append([]Path{}, paths[pick]...), path)
+			if turnsFor(g, candidate, g.Colonies[pick].Ants) >= turns(pick) {
+				done[pick] = true
+				continue
+			}
+		}
+		paths[pick] = append(paths[pick], path)
+		for i := 1; i < len(path)-1; i++ {
+			used[path[i]]++
+		}
+		for i := 0; i < len(path)-1; i++ {
+			usedLinks[[2]string{path[i], path[i+1]}] = true
+			usedLinks[[2]string{path[i+1], path[i]}] = true
+		}
+	}
+
+	fleets := make([]fleet, len(g.Colonies))
+	for i, c := range g.Colonies {
+		order.sortPaths(paths[i])
+		counts, _ := distributePaths(g, paths[i], c.Ants)
+		fleets[i] = fleet{paths: paths[i], counts: counts, ants: c.Ants}
+	}
+	return fleets, nil
+}
+
+// colonySearchGraph строит рабочую копию графа для searchShortPath
+// и оставшуюся вместимость комнат: старт — комната колонии, финиш —
+// виртуальный сток, в который ведут все финиши. Чужие старты и заполненные
+// комнаты закрыты, использованные туннели удалены, из финишей можно
+// пройти только в сток.
+func colonySearchGraph(g *Graph, start string, used map[string]int, usedLinks map[[2]string]bool) (*Graph, map[string]int) {
+	sg := &Graph{
+		Rooms:   make(map[string]*Room, len(g.Rooms)+1),
+		Links:   make(map[string][]string, len(g.Links)+1),
+		Start:   start,
+		End:     sinkName,
+		NumAnts: g.NumAnts,
+	}
+	left := make(map[string]int, len(g.Rooms))
+	for name, room := range g.Rooms {
+		sg.Rooms[name] = room
+		left[name] = g.Capacity(name) - used[name]
+	}
+	for _, c := range g.Colonies {
+		if c.Start != start {
+			left[c.Start] = 0
+		}
+	}
+	sg.Rooms[sinkName] = &Room{Name: sinkName}
+	for name, links := range g.Links {
+		for _, next := range links {
+			if !usedLinks[[2]string{name, next}] {
+				sg.Links[name] = append(sg.Links[name], next)
+			}
+		}
+	}
+	for _, end := range g.Ends {
+		sg.Links[end] = []string{sinkName}
+	}
+	return sg, left
+}
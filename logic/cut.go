@@ -0,0 +1,112 @@
+package logic
+
+import (
+	"context"
+	"sort"
+)
+
+// minVertexCut считает максимальный поток из стартов в финиши, когда
+// каждая промежуточная комната пропускает Capacity муравьёв (при
+// вместимости 1 — число вершинно-непересекающихся путей), и минимальный
+// разрез — комнаты, которые этот поток ограничивают. Если старт связан
+// с финишем напрямую, комнаты поток не ограничивают: bounded == false.
+func minVertexCut(g *Graph) (flow int, cut []string, bounded bool) {
+	return vertexCut(g, false)
+}
+
+// turnFlow — сколько муравьёв за ход может дойти до финишей: поток
+// minVertexCut, в котором туннель старт→финиш пропускает
+// directTunnelCapacity муравьёв колонии за ход.
+func turnFlow(g *Graph) int {
+	flow, _, _ := vertexCut(g, true)
+	return flow
+}
+
+// vertexCut считает поток и разрез; при limitDirect туннели старт→финиш
+// ограничены directTunnelCapacity, иначе не ограничены.
+func vertexCut(g *Graph, limitDirect bool) (flow int, cut []string, bounded bool) {
+	names := make([]string, 0, len(g.Rooms))
+	for name := range g.Rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+	isEnd := make(map[string]bool)
+	for _, end := range g.sinks() {
+		isEnd[end] = true
+	}
+	direct := make(map[string]int)
+	if limitDirect {
+		for _, c := range g.sources() {
+			direct[c.Start] = directTunnelCapacity(g.Rules, c.Ants)
+		}
+	}
+	// Поток не превосходит суммарной вместимости промежуточных комнат
+	// и прямых туннелей
+	inf := 1
+	for _, name := range names {
+		if !g.isTerminal(name) {
+			inf += g.Capacity(name)
+		}
+	}
+	for start, capacity := range direct {
+		for _, next := range g.Links[start] {
+			if isEnd[next] {
+				inf += capacity
+			}
+		}
+	}
+	in := func(r int) int { return 2 * r }
+	out := func(r int) int { return 2*r + 1 }
+	source, sink := 2*len(names), 2*len(names)+1
+	net := newFlowNet(2*len(names) + 2)
+	for r, name := range names {
+		roomCap := inf
+		if !g.isTerminal(name) {
+			roomCap = g.Capacity(name)
+		}
+		net.addEdge(in(r), out(r), roomCap, 0)
+		for _, next := range g.Links[name] {
+			linkCap := inf
+			if capacity, ok := direct[name]; ok && isEnd[next] {
+				linkCap = capacity
+			}
+			net.addEdge(out(r), in(index[next]), linkCap, 0)
+		}
+	}
+	for _, c := range g.sources() {
+		net.addEdge(source, in(index[c.Start]), inf, 0)
+	}
+	for _, end := range g.sinks() {
+		net.addEdge(out(index[end]), sink, inf, 0)
+	}
+
+	flow = net.maxFlow(context.Background(), source, sink, inf)
+	if flow >= inf {
+		return flow, nil, false
+	}
+	// Разрез — комнаты, вход которых достижим из истока в остаточной
+	// сети, а выход — нет
+	seen := make([]bool, len(net.adj))
+	seen[source] = true
+	queue := []int{source}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, e := range net.adj[v] {
+			if e.cap > 0 && !seen[e.to] {
+				seen[e.to] = true
+				queue = append(queue, e.to)
+			}
+		}
+	}
+	for r, name := range names {
+		if seen[in(r)] && !seen[out(r)] {
+			cut = append(cut, name)
+		}
+	}
+	return flow, cut, true
+}
@@ -4,124 +4,113 @@ import (
 	"sort"
 )
 
-// calcTime возвращает минимальное число ходов (turns) для заданных путей
-// и количества муравьёв. Формула учитывает выравнивание длин путей.
-func calcTime(paths []Path, ants int) int {
-	if len(paths) == 0 {
-		return 0
+// Единый распределитель муравьёв по путям ("налив воды").
+//
+// Путь длины L (в туннелях), на который за ход выходит r муравьёв,
+// за T ходов доставляет r*(T-L+1) муравьёв (0 при T < L): выпущенный
+// на ходу k муравей приходит на ходу L+k-1. Поэтому минимальное число
+// ходов — наименьшее T, при котором суммарная пропускная способность
+// путей не меньше числа муравьёв, а оптимальное распределение —
+// заполнить каждый путь до хода T и снять лишних с самых длинных путей
+// (каждый из них на ходу T доставляет последних r муравьёв). Именно так
+// выпускает муравьёв симуляция: каждый ход по r на каждый путь, пока
+// его счётчик не исчерпан, поэтому число ходов симуляции совпадает с T.
+
+// distribute возвращает число муравьёв на каждом пути (в порядке
+// lengths) и минимальное число ходов. rates[i] — сколько муравьёв
+// выходит на путь i за ход.
+func distribute(lengths, rates []int, ants int) ([]int, int) {
+	counts := make([]int, len(lengths))
+	if len(lengths) == 0 || ants <= 0 {
+		return counts, 0
 	}
-	lengths := make([]int, len(paths))
-	minL, maxL := 0, 0
-	for i, p := range paths {
-		l := len(p) - 1
-		lengths[i] = l
-		if i == 0 || l < minL {
-			minL = l
-		}
-		if l > maxL {
-			maxL = l
+	capacity := func(turns int) int {
+		total := 0
+		for i, l := range lengths {
+			if turns >= l {
+				total += rates[i] * (turns - l + 1)
+				if total >= ants {
+					return total
+				}
+			}
 		}
+		return total
 	}
-	sum := 0
+
+	// Кратчайший путь в одиночку укладывается в minL+ants-1 ходов
+	minL := lengths[0]
 	for _, l := range lengths {
-		sum += maxL - l
+		minL = min(minL, l)
 	}
-	antsPer := maxL - minL + (ants-sum)/len(paths)
-	if (ants-sum)%len(paths) > 0 {
-		antsPer++
+	lo, hi := minL, minL+ants-1
+	for lo < hi {
+		mid := (lo + hi) / 2
+		if capacity(mid) >= ants {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
 	}
-	return minL + antsPer - 1
-}
+	turns := lo
 
-// calcTimeAndDistribute считает число ходов и распределяет муравьёв
-// по путям, когда пути получены методом Суурбалле (сортировка по длине).
-func calcTimeAndDistribute(paths []Path, ants int) (int, []int) {
-	if len(paths) == 0 {
-		return 0, []int{}
+	surplus := -ants
+	for i, l := range lengths {
+		if turns >= l {
+			counts[i] = rates[i] * (turns - l + 1)
+			surplus += counts[i]
+		}
 	}
-	pathInfos := make([]struct {
-		path  Path
-		index int
-	}, len(paths))
-	for i, p := range paths {
-		pathInfos[i] = struct {
-			path  Path
-			index int
-		}{path: p, index: i}
+	// Лишних снимаем с хода T на самых длинных путях (при равенстве —
+	// с более поздних): среди приходящих последними их муравьи дольше в пути.
+	order := make([]int, len(lengths))
+	for i := range order {
+		order[i] = i
 	}
-	sort.Slice(pathInfos, func(i, j int) bool {
-		return len(pathInfos[i].path) < len(pathInfos[j].path)
+	sort.SliceStable(order, func(a, b int) bool {
+		if lengths[order[a]] != lengths[order[b]] {
+			return lengths[order[a]] > lengths[order[b]]
+		}
+		return order[a] > order[b]
 	})
-	lengths := make([]int, len(paths))
-	for i, pi := range pathInfos {
-		lengths[i] = len(pi.path) - 1
-	}
-	result := make([]int, len(paths))
-	if lengths[0] == 1 {
-		result[pathInfos[0].index] = ants
-		return 1, result
-	}
-	maxLength := lengths[len(lengths)-1]
-	remainingAnts := ants
-	for i := 0; i < len(paths); i++ {
-		baseAnts := maxLength - lengths[i]
-		result[pathInfos[i].index] = baseAnts
-		remainingAnts -= baseAnts
-	}
-	if remainingAnts > 0 {
-		antsPerPath := remainingAnts / len(paths)
-		remainder := remainingAnts % len(paths)
-		for i := 0; i < len(paths); i++ {
-			result[pathInfos[i].index] += antsPerPath
-			if i < remainder {
-				result[pathInfos[i].index]++
-			}
+	for _, i := range order {
+		if surplus == 0 {
+			break
 		}
+		if counts[i] == 0 {
+			continue
+		}
+		take := min(surplus, rates[i], counts[i])
+		counts[i] -= take
+		surplus -= take
 	}
-	steps := lengths[0] + (ants+len(paths)-1)/len(paths) - 1
-	return steps, result
+	return counts, turns
 }
 
-// distributeAnts распределяет муравьёв по путям для набора путей из DFS
-// при известном числе ходов (turns), стремясь сбалансировать нагрузки.
-func distributeAnts(paths []Path, ants, turns int) []int {
-	n := len(paths)
-	counts := make([]int, n)
+// pathRates возвращает длины путей в туннелях и число муравьёв,
+// выходящих на каждый путь за ход: по одному, кроме прямого
+// туннеля старт→финиш (см. directTunnelCapacity).
+func pathRates(g *Graph, paths []Path, ants int) (lengths, rates []int) {
+	lengths = make([]int, len(paths))
+	rates = make([]int, len(paths))
 	for i, p := range paths {
-		length := len(p) - 1
-		c := turns - (length - 1)
-		if c < 0 {
-			c = 0
-		}
-		counts[i] = c
-	}
-	sum := 0
-	for _, c := range counts {
-		sum += c
-	}
-	for sum < ants {
-		idx := 0
-		for i := 1; i < n; i++ {
-			if len(paths[i]) > len(paths[idx]) {
-				idx = i
-			}
+		lengths[i] = len(p) - 1
+		rates[i] = 1
+		if len(p) == 2 {
+			rates[i] = max(1, directTunnelCapacity(g.Rules, ants))
 		}
-		counts[idx]++
-		sum++
 	}
-	for sum > ants {
-		idx := 0
-		for i := 1; i < n; i++ {
-			if counts[i] < counts[idx] {
-				idx = i
-			}
-		}
-		if counts[idx] > 0 {
-			counts[idx]--
-			sum--
-		} else {
-			break
-		}
-	}
-	return counts
+	return lengths, rates
+}
+
+// distributePaths распределяет ants муравьёв по путям: возвращает
+// счётчики по путям и число ходов.
+func distributePaths(g *Graph, paths []Path, ants int) ([]int, int) {
+	lengths, rates := pathRates(g, paths, ants)
+	return distribute(lengths, rates, ants)
+}
+
+// turnsFor — минимальное число ходов для ants муравьёв на путях paths.
+func turnsFor(g *Graph, paths []Path, ants int) int {
+	_, turns := distributePaths(g, paths, ants)
+	return turns
 }
@@ -0,0 +1,130 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"testing"
+)
+
+// TestDistributeBruteForce сверяет распределитель с полным перебором:
+// для всех наборов до четырёх путей длиной от 1 до 5 туннелей (прямой
+// туннель — не больше одного) и от 1 до 8 муравьёв число ходов должно
+// быть минимальным, счётчики в сумме — давать всех муравьёв, а план
+// (Schedule) и симуляция на графе из непересекающихся цепочек таких
+// длин — занимать ровно столько же ходов.
+func TestDistributeBruteForce(t *testing.T) {
+	const maxAnts = 8
+	var lengths []int
+	var walk func(from int)
+	walk = func(from int) {
+		if len(lengths) > 0 {
+			for ants := 1; ants <= maxAnts; ants++ {
+				checkDistribution(t, lengths, ants)
+			}
+		}
+		if len(lengths) == 4 {
+			return
+		}
+		for l := from; l <= 5; l++ {
+			if l == 1 && len(lengths) > 0 && lengths[0] == 1 {
+				continue
+			}
+			lengths = append(lengths, l)
+			walk(l)
+			lengths = lengths[:len(lengths)-1]
+		}
+	}
+	walk(1)
+}
+
+func checkDistribution(t *testing.T, lengths []int, ants int) {
+	t.Helper()
+	g, paths := chainGraph(lengths, ants)
+	counts, turns := distributePaths(g, paths, ants)
+	_, rates := pathRates(g, paths, ants)
+
+	sum, arrival := 0, 0
+	for i, c := range counts {
+		sum += c
+		if c > 0 {
+			arrival = max(arrival, lengths[i]+(c+rates[i]-1)/rates[i]-1)
+		}
+	}
+	if sum != ants || arrival != turns {
+		t.Errorf("lengths %v, %d ants: counts %v sum to %d and finish at %d, reported %d", lengths, ants, counts, sum, arrival, turns)
+		return
+	}
+	if best := bruteForceTurns(lengths, rates, ants); best != turns {
+		t.Errorf("lengths %v, %d ants: %d turns, brute force finds %d", lengths, ants, turns, best)
+	}
+
+	fleets := []fleet{{paths: paths, counts: counts, ants: ants}}
+	if sched := buildSchedule(g, fleets); sched.Turns != turns {
+		t.Errorf("lengths %v, %d ants: schedule takes %d turns, distribution predicts %d", lengths, ants, sched.Turns, turns)
+	}
+	moves, _, err := moveAnts(context.Background(), g, fleets, nil)
+	if err != nil {
+		t.Errorf("lengths %v, %d ants: %v", lengths, ants, err)
+		return
+	}
+	if len(moves) != turns {
+		t.Errorf("lengths %v, %d ants: simulation takes %d turns, distribution predicts %d", lengths, ants, len(moves), turns)
+	}
+	if err := validateMoves(g, moves); err != nil {
+		t.Errorf("lengths %v, %d ants: %v", lengths, ants, err)
+	}
+}
+
+// bruteForceTurns перебирает все разбиения муравьёв по путям.
+func bruteForceTurns(lengths, rates []int, ants int) int {
+	best := -1
+	var split func(i, left, worst int)
+	split = func(i, left, worst int) {
+		if i == len(lengths)-1 {
+			if left > 0 {
+				worst = max(worst, lengths[i]+(left+rates[i]-1)/rates[i]-1)
+			}
+			if best < 0 || worst < best {
+				best = worst
+			}
+			return
+		}
+		for c := 0; c <= left; c++ {
+			w := worst
+			if c > 0 {
+				w = max(w, lengths[i]+(c+rates[i]-1)/rates[i]-1)
+			}
+			split(i+1, left-c, w)
+		}
+	}
+	split(0, ants, 0)
+	return best
+}
+
+// chainGraph строит граф из непересекающихся цепочек заданных длин
+// между общими стартом и финишем и возвращает его вместе с путями.
+func chainGraph(lengths []int, ants int) (*Graph, []Path) {
+	g := &Graph{
+		Rooms:   map[string]*Room{"s": {Name: "s"}, "e": {Name: "e"}},
+		Links:   make(map[string][]string),
+		Start:   "s",
+		End:     "e",
+		NumAnts: ants,
+	}
+	paths := make([]Path, len(lengths))
+	for i, l := range lengths {
+		path := Path{"s"}
+		for k := 1; k < l; k++ {
+			name := fmt.Sprintf("p%d_%d", i, k)
+			g.Rooms[name] = &Room{Name: name, Capacity: 1}
+			path = append(path, name)
+		}
+		path = append(path, "e")
+		for k := 0; k+1 < len(path); k++ {
+			g.Links[path[k]] = append(g.Links[path[k]], path[k+1])
+			g.Links[path[k+1]] = append(g.Links[path[k+1]], path[k])
+		}
+		paths[i] = path
+	}
+	return g, paths
+}
@@ -0,0 +1,69 @@
+package logic
+
+import (
+	"fmt"
+)
+
+// Правка карты в памяти (например, из "lem-in repl"). Методы проверяют
+// то же, что и парсер, и не трогают g.Input — исходный текст карты;
+// актуальный текст возвращает Lines.
+
+// AddRoom добавляет промежуточную комнату вместимости 1.
+func (g *Graph) AddRoom(name string, x, y int) error {
+	if err := checkRoomName(name); err != nil {
+		return err
+	}
+	if _, ok := g.Rooms[name]; ok {
+		return fmt.Errorf("room %s already exists", name)
+	}
+	g.Rooms[name] = &Room{Name: name, X: x, Y: y, Capacity: 1}
+	return nil
+}
+
+// AddLink добавляет двусторонний туннель a-b с весом 1.
+func (g *Graph) AddLink(a, b string) error {
+	if a == b {
+		return fmt.Errorf("self-loop %s-%s", a, b)
+	}
+	for _, name := range []string{a, b} {
+		if _, ok := g.Rooms[name]; !ok {
+			return fmt.Errorf("room %s not found", name)
+		}
+	}
+	if hasLink(g, a, b) || hasLink(g, b, a) {
+		return fmt.Errorf("rooms %s and %s are already linked", a, b)
+	}
+	g.Links[a] = append(g.Links[a], b)
+	g.Links[b] = append(g.Links[b], a)
+	return nil
+}
+
+// RemoveLink удаляет все туннели между a и b в обе стороны.
+func (g *Graph) RemoveLink(a, b string) error {
+	if !hasLink(g, a, b) && !hasLink(g, b, a) {
+		return fmt.Errorf("rooms %s and %s are not linked", a, b)
+	}
+	for _, pair := range [][2]string{{a, b}, {b, a}} {
+		var kept []string
+		for _, to := range g.Links[pair[0]] {
+			if to != pair[1] {
+				kept = append(kept, to)
+			}
+		}
+		g.Links[pair[0]] = kept
+		delete(g.Weights, pair)
+	}
+	return nil
+}
+
+// SetAnts меняет число муравьёв классической карты.
+func (g *Graph) SetAnts(n int) error {
+	if n <= 0 {
+		return fmt.Errorf("invalid number of ants: %d", n)
+	}
+	if len(g.Colonies) > 0 {
+		return fmt.Errorf("ants are set per colony in multi-colony mode")
+	}
+	g.NumAnts = n
+	return nil
+}
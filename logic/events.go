@@ -0,0 +1,196 @@
+package logic
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Event — событие сценария: перед ходом Turn закрывается туннель Link
+// или комната Room (задано ровно одно из двух).
+type Event struct {
+	Turn int
+	Link [2]string
+	Room string
+	Line int // номер строки в файле событий
+}
+
+func (ev Event) String() string {
+	if ev.Room != "" {
+		return "close room " + ev.Room
+	}
+	return "close " + ev.Link[0] + "-" + ev.Link[1]
+}
+
+// Reroute — точка перестроения пути после события.
+type Reroute struct {
+	Turn  int    `json:"turn"`          // ход, перед которым сработало событие
+	Event string `json:"event"`         // описание события, например "close a-b"
+	Ant   string `json:"ant,omitempty"` // обозначение муравья; пусто — для ещё не вышедших муравьёв
+	Path  Path   `json:"path"`          // новый путь от текущего положения до финиша
+}
+
+// ParseEvents разбирает файл событий. Поддерживаются строки
+// "turn N: close a-b" и "turn N: close room X"; пустые строки
+// и комментарии (#) пропускаются.
+func ParseEvents(text string) ([]Event, error) {
+	var events []Event
+	for i, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		head, action, ok := strings.Cut(line, ":")
+		fields := strings.Fields(head)
+		if !ok || len(fields) != 2 || fields[0] != "turn" {
+			return nil, &ParseError{Line: i + 1, Msg: "invalid event", Text: line}
+		}
+		turn, err := strconv.Atoi(fields[1])
+		if err != nil || turn < 1 {
+			return nil, &ParseError{Line: i + 1, Msg: "invalid event turn", Text: line}
+		}
+		ev := Event{Turn: turn, Line: i + 1}
+		args := strings.Fields(action)
+		switch {
+		case len(args) == 3 && args[0] == "close" && args[1] == "room":
+			ev.Room = args[2]
+		case len(args) == 2 && args[0] == "close":
+			a, b, ok := strings.Cut(args[1], "-")
+			if !ok || a == "" || b == "" {
+				return nil, &ParseError{Line: i + 1, Msg: "invalid event link", Text: line}
+			}
+			ev.Link = [2]string{a, b}
+		default:
+			return nil, &ParseError{Line: i + 1, Msg: "invalid event action", Text: line}
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+// checkEvents проверяет, что события ссылаются на существующие
+// комнаты и туннели и не закрывают старт или финиш.
+func checkEvents(g *Graph, events []Event) error {
+	for _, ev := range events {
+		if ev.Room != "" {
+			if _, ok := g.Rooms[ev.Room]; !ok {
+				return fmt.Errorf("event at line %d: room %s not found", ev.Line, ev.Room)
+			}
+			if g.isTerminal(ev.Room) {
+				return fmt.Errorf("event at line %d: cannot close start or end room %s", ev.Line, ev.Room)
+			}
+			continue
+		}
+		a, b := ev.Link[0], ev.Link[1]
+		if tunnelEntry(g, a, b) == "" && tunnelEntry(g, b, a) == "" {
+			return fmt.Errorf("event at line %d: tunnel %s-%s not found", ev.Line, a, b)
+		}
+	}
+	return nil
+}
+
+// tunnelEntry возвращает первую комнату на пути по туннелю from→to:
+// саму to или первый сегмент взвешенного туннеля. Пусто — туннеля нет.
+func tunnelEntry(g *Graph, from, to string) string {
+	for _, next := range g.Links[from] {
+		if next == to ||
+			strings.HasPrefix(next, from+"-"+to+":") ||
+			strings.HasPrefix(next, to+"-"+from+":") ||
+			strings.HasPrefix(next, from+"->"+to+":") {
+			return next
+		}
+	}
+	return ""
+}
+
+// apply закрывает элемент графа и перестраивает пути муравьёв, которые
+// ещё не прошли его. Муравьи внутри закрытой комнаты или туннеля
+// могут их покинуть: закрывается только вход.
+func (s *simulator) apply(ev Event) {
+	g := s.g
+	if ev.Room != "" {
+		for name, links := range g.Links {
+			g.Links[name] = removeLink(links, ev.Room)
+		}
+	} else {
+		a, b := ev.Link[0], ev.Link[1]
+		if entry := tunnelEntry(g, a, b); entry != "" {
+			g.Links[a] = removeLink(g.Links[a], entry)
+		}
+		if entry := tunnelEntry(g, b, a); entry != "" {
+			g.Links[b] = removeLink(g.Links[b], entry)
+		}
+	}
+
+	for f := range s.fleets {
+		start := g.sources()[f].Start
+		for i := range s.active[f] {
+			a := &s.active[f][i]
+			if pathIntact(g, a.path[a.pos:]) {
+				continue
+			}
+			if path, ok := shortestPathFrom(g, a.path[a.pos], start); ok {
+				a.path, a.pos = path, 0
+				s.reroutes = append(s.reroutes, Reroute{Turn: s.turn, Event: ev.String(), Ant: antLabel(g, f, a.id), Path: path})
+			}
+		}
+		fl := &s.fleets[f]
+		for i, path := range fl.paths {
+			if fl.counts[i] == 0 || pathIntact(g, path) {
+				continue
+			}
+			if newPath, ok := shortestPathFrom(g, path[0], start); ok {
+				fl.paths[i] = newPath
+				s.reroutes = append(s.reroutes, Reroute{Turn: s.turn, Event: ev.String(), Path: newPath})
+			}
+		}
+	}
+}
+
+// pathIntact сообщает, что все туннели пути ещё открыты.
+func pathIntact(g *Graph, path Path) bool {
+	for i := 0; i+1 < len(path); i++ {
+		if !hasLink(g, path[i], path[i+1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// shortestPathFrom ищет кратчайший (по числу туннелей) путь от комнаты
+// from до любого финиша по текущему графу. Путь может пройти через
+// старт start своей колонии: муравей, отрезанный от финиша, возвращается
+// в обход. Старты других колоний путь обходит.
+func shortestPathFrom(g *Graph, from, start string) (Path, bool) {
+	isEnd := make(map[string]bool)
+	for _, end := range g.sinks() {
+		isEnd[end] = true
+	}
+	parent := map[string]string{from: ""}
+	// Через старты других колоний не ходят: там стоят их муравьи
+	for _, c := range g.Colonies {
+		if c.Start != start && c.Start != from {
+			parent[c.Start] = ""
+		}
+	}
+	queue := []string{from}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if isEnd[cur] {
+			path := Path{cur}
+			for r := parent[cur]; r != ""; r = parent[r] {
+				path = append(Path{r}, path...)
+			}
+			return path, true
+		}
+		for _, next := range g.Links[cur] {
+			if _, seen := parent[next]; seen {
+				continue
+			}
+			parent[next] = cur
+			queue = append(queue, next)
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,155 @@
+package logic
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// twoColonies — две колонии; после закрытия a-e кратчайший обход
+// из s1 идёт через старт s2, но муравьям s1 туда нельзя.
+const twoColonies = `2
+##start 1
+s1 0 0
+##start 1
+s2 0 2
+a 1 0
+b 1 2
+x 1 4
+y 2 4
+z 3 4
+##end
+e 4 0
+s1-a
+a-e
+s1-s2
+s2-b
+b-e
+s1-x
+x-y
+y-z
+z-e
+`
+
+func TestRerouteAvoidsOtherStarts(t *testing.T) {
+	g, err := Parse(twoColonies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := ParseEvents("turn 1: close a-e\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := Solve(context.Background(), g, Options{Events: events})
+	if resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	if len(resp.Reroutes) == 0 {
+		t.Fatal("no reroutes, want the s1 path rebuilt")
+	}
+	for _, r := range resp.Reroutes {
+		if slices.Contains(r.Path, "s2") {
+			t.Errorf("reroute %s passes through the other colony's start", r.Path)
+		}
+	}
+	if len(resp.Output) != 4 {
+		t.Errorf("got %d turns, want 4 via s1-x-y-z-e", len(resp.Output))
+	}
+}
+
+// idleMap — после событий на ходах 2 и 3 муравьи L2 (в x) и L3 (в y)
+// ждут друг друга, пока остальные доходят до финиша; ход 13 пустой,
+// а на ходу 14 закрытие z1-z2 уводит L3 через w.
+const idleMap = `12
+##start
+s 0 0
+##end
+e 9 0
+a1 1 1
+y 2 1
+x 3 1
+z1 4 1
+z2 5 1
+z3 6 1
+b1 1 2
+w 2 2
+c1 1 3
+q 2 3
+s-a1
+a1-y
+y-x
+x-z1
+z1-z2
+z2-z3
+z3-e
+s-b1
+b1-w
+w-e
+y-w
+s-c1
+c1-q
+q-e
+c1-x
+x-q
+`
+
+func TestIdleTurnKeepsNumbering(t *testing.T) {
+	g, err := Parse(idleMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := ParseEvents("turn 2: close c1-q\nturn 3: close x-q\nturn 14: close z1-z2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := Solve(context.Background(), g, Options{Events: events})
+	if resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	if len(resp.Output) != 17 {
+		t.Fatalf("got %d turns, want 17", len(resp.Output))
+	}
+	if resp.Output[12] != "" {
+		t.Fatalf("turn 13 is %q, want an empty turn", resp.Output[12])
+	}
+	last := resp.Reroutes[len(resp.Reroutes)-1]
+	if last.Turn != 14 || resp.Output[13] != "L3-w" {
+		t.Errorf("reroute at turn %d, turn 14 %q; want L3 rerouted and moving to w on turn 14", last.Turn, resp.Output[13])
+	}
+
+	// Потоковая симуляция нумерует те же ходы
+	n := 0
+	for turn, err := range Simulate(context.Background(), g, Options{Events: events}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n++; turn.Number != n {
+			t.Fatalf("streamed turn %d has number %d", n, turn.Number)
+		}
+	}
+}
+
+// TestPlanRun проверяет, что план можно запускать повторно: события
+// не меняют его пути и граф.
+func TestPlanRun(t *testing.T) {
+	g, err := Parse(idleMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := ParseEvents("turn 2: close c1-q\nturn 3: close x-q\nturn 14: close z1-z2\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := Options{Events: events}
+	want := Solve(context.Background(), g, opts)
+	p, err := NewPlan(context.Background(), g, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for run := 1; run <= 2; run++ {
+		if got := p.Run(context.Background()); !slices.Equal(got.Output, want.Output) || len(got.Reroutes) != len(want.Reroutes) {
+			t.Fatalf("run %d: %d turns and %d reroutes, Solve gives %d and %d",
+				run, len(got.Output), len(got.Reroutes), len(want.Output), len(want.Reroutes))
+		}
+	}
+}
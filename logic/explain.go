@@ -0,0 +1,110 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// explainSetLimit — сколько наборов путей перебора выводится в пояснении;
+// улучшения лучшего набора выводятся всегда.
+const explainSetLimit = 30
+
+// trace собирает пояснения решателя (Options.Explain). Трасса передаётся
+// через контекст, как httptrace, чтобы не менять сигнатуры решателей;
+// методы безопасны для nil, поэтому без запроса пояснений она ничего не стоит.
+type trace struct {
+	lines []string
+}
+
+type traceKey struct{}
+
+func withTrace(ctx context.Context, t *trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, t)
+}
+
+// traceFrom возвращает трассу из контекста или nil.
+func traceFrom(ctx context.Context) *trace {
+	t, _ := ctx.Value(traceKey{}).(*trace)
+	return t
+}
+
+func (t *trace) add(format string, args ...any) {
+	if t != nil {
+		t.lines = append(t.lines, fmt.Sprintf(format, args...))
+	}
+}
+
+// strategy объясняет выбор choosePathsHybrid по её порогам.
+func (t *trace) strategy(ants, rooms int, complexity float64, disjoint bool) {
+	if t == nil {
+		return
+	}
+	if !disjoint {
+		t.add("strategy: DFS enumeration + branch and bound (ants %d <= 100, rooms %d <= 30, links per room %.2f <= 2.50)",
+			ants, rooms, complexity)
+		return
+	}
+	var why []string
+	if ants > 100 {
+		why = append(why, fmt.Sprintf("ants %d > 100", ants))
+	}
+	if rooms > 30 {
+		why = append(why, fmt.Sprintf("rooms %d > 30", rooms))
+	}
+	if complexity > 2.5 {
+		why = append(why, fmt.Sprintf("links per room %.2f > 2.50", complexity))
+	}
+	t.add("strategy: successive shortest paths (findDisjointPaths) because %s", strings.Join(why, ", "))
+}
+
+// paths перечисляет пути с номерами.
+func (t *trace) paths(title string, paths []Path) {
+	if t == nil {
+		return
+	}
+	t.add("%s: %d", title, len(paths))
+	for i, p := range paths {
+		if i == explainSetLimit {
+			t.add("  ... %d more", len(paths)-i)
+			break
+		}
+		t.add("  #%d %s (%d tunnels)", i+1, strings.Join(p, "-"), len(p)-1)
+	}
+}
+
+// candidateSet записывает оценку набора путей при переборе choosePathsDFS;
+// idx — номера путей среди кандидатов (с нуля).
+func (t *trace) candidateSet(idx []int, turns int, best bool, evaluated int) {
+	if t == nil || (evaluated > explainSetLimit && !best) {
+		return
+	}
+	names := make([]string, len(idx))
+	for i, k := range idx {
+		names[i] = fmt.Sprintf("#%d", k+1)
+	}
+	mark := ""
+	if best {
+		mark = " (new best)"
+	}
+	t.add("  set {%s}: %d turns%s", strings.Join(names, " "), turns, mark)
+}
+
+// distribution объясняет распределение муравьёв по путям.
+func (t *trace) distribution(g *Graph, paths []Path, counts []int, ants, turns int) {
+	if t == nil {
+		return
+	}
+	lengths, rates := pathRates(g, paths, ants)
+	t.add("distribution: %d ants in %d turns — the least T at which the paths deliver everyone;", ants, turns)
+	t.add("  a path of L tunnels carries rate*(T-L+1) ants, the surplus is taken from the longest paths")
+	for i, p := range paths {
+		arrival := 0
+		if counts[i] > 0 {
+			arrival = lengths[i] + (counts[i]+rates[i]-1)/rates[i] - 1
+		}
+		t.add("  path %d: %s (%d tunnels, %d per turn): %d ants, last arrives on turn %d",
+			i+1, strings.Join(p, "-"), lengths[i], rates[i], counts[i], arrival)
+	}
+	t.add("  the distribution is exact: the simulation launches these counts and ends on turn %d", turns)
+}
@@ -0,0 +1,68 @@
+package logic
+
+import (
+	"context"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// explainOf решает пример с пояснениями и возвращает их.
+func explainOf(t *testing.T, name string) []string {
+	t.Helper()
+	data, err := os.ReadFile("../test_case/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, err := Parse(string(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := Solve(context.Background(), g, Options{Explain: true})
+	if resp.Error != "" {
+		t.Fatal(resp.Error)
+	}
+	return resp.Explain
+}
+
+// TestExplainDFS проверяет пояснение перебора: порог стратегии,
+// отметки улучшений и итоговое распределение example01.
+func TestExplainDFS(t *testing.T) {
+	lines := explainOf(t, "example01.txt")
+	for _, want := range []string{
+		"strategy: DFS enumeration + branch and bound (ants 10 <= 100, rooms 14 <= 30, links per room 2.43 <= 2.50)",
+		"  set {#1}: 13 turns (new best)",
+		"  set {#2}: 13 turns",
+		"  set {#3 #4 #5}: 8 turns (new best)",
+		"distribution: 10 ants in 8 turns — the least T at which the paths deliver everyone;",
+		"  path 1: start-t-E-a-m-end (5 tunnels, 1 per turn): 4 ants, last arrives on turn 8",
+		"  the distribution is exact: the simulation launches these counts and ends on turn 8",
+	} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("missing %q in\n%s", want, strings.Join(lines, "\n"))
+		}
+	}
+}
+
+// TestExplainDisjoint проверяет причину выбора findDisjointPaths.
+func TestExplainDisjoint(t *testing.T) {
+	lines := explainOf(t, "example05.txt")
+	want := "strategy: successive shortest paths (findDisjointPaths) because links per room 2.59 > 2.50"
+	if len(lines) == 0 || lines[0] != want {
+		t.Fatalf("first line %q, want %q", lines[:min(1, len(lines))], want)
+	}
+	if !slices.Contains(lines, "  path 3: start-C0-C1-C2-C3-I4-I5-end (7 tunnels, 1 per turn): 1 ants, last arrives on turn 7") {
+		t.Errorf("no distribution line for path 3 in\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestExplainOff(t *testing.T) {
+	g, err := Parse(paddedMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp := Solve(context.Background(), g, Options{}); resp.Explain != nil {
+		t.Errorf("explain without Options.Explain: %q", resp.Explain)
+	}
+}
@@ -0,0 +1,246 @@
+package logic
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Lines возвращает текст карты, который разбирается в тот же граф
+// (см. Equivalent): число муравьёв, старты, финиши, остальные комнаты
+// по имени и туннели по именам концов. Комментарии не сохраняются,
+// туннели берутся из текущих связей, поэтому Lines отражает правки карты.
+func (g *Graph) Lines() []string {
+	return g.render(adjacencyDecls(g), nil)
+}
+
+// Format возвращает каноническую запись только что разобранной карты
+// в том же порядке, что и Lines. Комментарии ввода остаются перед
+// объявлением, за которым шли (перед ##start/##end его комнаты), а
+// комментарии в конце ввода — в конце. Пустые строки и строки, которые
+// парсер пропускает, отбрасываются.
+func Format(g *Graph) []string {
+	if g.Source == nil {
+		return g.Lines()
+	}
+	comments, trailing := g.comments()
+	return append(g.render(g.Source.Links, comments), trailing...)
+}
+
+// render записывает карту; comments — комментарии по номеру строки
+// объявления, к которому они относятся.
+func (g *Graph) render(links []LinkDecl, comments map[int][]string) []string {
+	var lines []string
+	line := func(name string) int {
+		if g.Source == nil {
+			return 0
+		}
+		return g.Source.Rooms[name]
+	}
+	emit := func(at int, text ...string) {
+		if at > 0 {
+			lines = append(lines, comments[at]...)
+		}
+		lines = append(lines, text...)
+	}
+	// ##capacity пишется только перед промежуточными комнатами:
+	// у старта и финиша вместимости нет
+	room := func(name, command string) {
+		var text []string
+		r := g.Rooms[name]
+		if command != "" {
+			text = append(text, command)
+		} else if r.Capacity > 1 {
+			text = append(text, fmt.Sprintf("##capacity %d", r.Capacity))
+		}
+		emit(line(name), append(text, fmt.Sprintf("%s %d %d", name, r.X, r.Y))...)
+	}
+
+	antsLine := 0
+	if g.Source != nil {
+		antsLine = g.Source.AntsLine
+	}
+	emit(antsLine, strconv.Itoa(g.NumAnts))
+	if len(g.Colonies) > 0 {
+		for _, c := range g.Colonies {
+			room(c.Start, fmt.Sprintf("##start %d", c.Ants))
+		}
+	} else {
+		room(g.Start, "##start")
+	}
+	for _, end := range g.sinks() {
+		room(end, "##end")
+	}
+	for _, name := range g.innerRooms() {
+		room(name, "")
+	}
+	for _, decl := range sortDecls(links) {
+		emit(decl.Line, linkText(decl))
+	}
+	return lines
+}
+
+// innerRooms возвращает промежуточные комнаты по имени.
+func (g *Graph) innerRooms() []string {
+	names := make([]string, 0, len(g.Rooms))
+	for name := range g.Rooms {
+		if !g.isTerminal(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// sortDecls упорядочивает туннели по концам (linkKey), двусторонний
+// раньше однонаправленного между теми же комнатами.
+func sortDecls(links []LinkDecl) []LinkDecl {
+	sorted := append([]LinkDecl{}, links...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ki, kj := linkKey(sorted[i]), linkKey(sorted[j])
+		if ki != kj {
+			return ki[0] < kj[0] || ki[0] == kj[0] && ki[1] < kj[1]
+		}
+		return !sorted[i].Directed && sorted[j].Directed
+	})
+	return sorted
+}
+
+// linkKey — концы туннеля для сортировки: у двустороннего — по имени.
+func linkKey(decl LinkDecl) [2]string {
+	if decl.Directed || decl.From < decl.To {
+		return [2]string{decl.From, decl.To}
+	}
+	return [2]string{decl.To, decl.From}
+}
+
+// linkText записывает туннель: "a-b[:w]" или "a->b[:w]".
+func linkText(decl LinkDecl) string {
+	key, sep := linkKey(decl), "-"
+	if decl.Directed {
+		sep = "->"
+	}
+	text := key[0] + sep + key[1]
+	if decl.Weight > 1 {
+		text += ":" + strconv.Itoa(decl.Weight)
+	}
+	return text
+}
+
+// adjacencyDecls восстанавливает объявления туннелей по связям графа:
+// пара встречных связей с равным весом — двусторонний туннель,
+// остальные — однонаправленные. Повторённые туннели повторяются.
+func adjacencyDecls(g *Graph) []LinkDecl {
+	count := make(map[[2]string]int)
+	for from, links := range g.Links {
+		for _, to := range links {
+			count[[2]string{from, to}]++
+		}
+	}
+	var decls []LinkDecl
+	for pair, n := range count {
+		a, b := pair[0], pair[1]
+		both := 0
+		if g.Weight(a, b) == g.Weight(b, a) {
+			both = min(n, count[[2]string{b, a}])
+		}
+		for k := 0; k < n; k++ {
+			decl := LinkDecl{From: a, To: b, Weight: g.Weight(a, b), Directed: k >= both}
+			// Двусторонний туннель учитывается один раз — со стороны меньшего имени
+			if decl.Directed || a < b {
+				decls = append(decls, decl)
+			}
+		}
+	}
+	return decls
+}
+
+// comments привязывает строки-комментарии ввода к следующему объявлению
+// (числу муравьёв, комнате или туннелю); комментарии после последнего
+// объявления возвращаются отдельно.
+func (g *Graph) comments() (map[int][]string, []string) {
+	decl := map[int]bool{g.Source.AntsLine: true}
+	for _, line := range g.Source.Rooms {
+		decl[line] = true
+	}
+	for _, link := range g.Source.Links {
+		decl[link.Line] = true
+	}
+	byLine := make(map[int][]string)
+	var pending []string
+	for i, raw := range g.Input {
+		line := strings.TrimSpace(raw)
+		if decl[i+1] {
+			if len(pending) > 0 {
+				byLine[i+1], pending = pending, nil
+			}
+			continue
+		}
+		if isComment(line) {
+			pending = append(pending, line)
+		}
+	}
+	return byLine, pending
+}
+
+// isComment сообщает, что строка — комментарий, а не команда парсера.
+func isComment(line string) bool {
+	if !strings.HasPrefix(line, "#") {
+		return false
+	}
+	cmd := strings.Fields(line)[0]
+	return cmd != "##start" && cmd != "##end" && cmd != "##capacity"
+}
+
+// Equivalent сообщает, что графы описывают одну и ту же карту: совпадают
+// комнаты с координатами, вместимостью и метками, туннели с весами,
+// старты, финиши и муравьи. Порядок связей в списках смежности
+// не важен; исходный текст, номера строк и правила не сравниваются.
+func Equivalent(a, b *Graph) bool {
+	if a.NumAnts != b.NumAnts || a.Start != b.Start || a.End != b.End ||
+		len(a.Rooms) != len(b.Rooms) || len(a.Colonies) != len(b.Colonies) || len(a.Ends) != len(b.Ends) {
+		return false
+	}
+	for i := range a.Colonies {
+		if a.Colonies[i] != b.Colonies[i] {
+			return false
+		}
+	}
+	for i := range a.Ends {
+		if a.Ends[i] != b.Ends[i] {
+			return false
+		}
+	}
+	for name, ra := range a.Rooms {
+		rb, ok := b.Rooms[name]
+		if !ok || ra.Name != rb.Name || ra.X != rb.X || ra.Y != rb.Y || ra.Capacity != rb.Capacity ||
+			!slices.Equal(ra.Tags, rb.Tags) {
+			return false
+		}
+	}
+	if len(a.Weights) != len(b.Weights) {
+		return false
+	}
+	for k, w := range a.Weights {
+		if b.Weights[k] != w {
+			return false
+		}
+	}
+	for name := range a.Rooms {
+		la := append([]string{}, a.Links[name]...)
+		lb := append([]string{}, b.Links[name]...)
+		if len(la) != len(lb) {
+			return false
+		}
+		sort.Strings(la)
+		sort.Strings(lb)
+		for i := range la {
+			if la[i] != lb[i] {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,45 @@
+package logic
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// GenerateMap строит карту примерно из rooms комнат: старт и финиш
+// соединены w ≈ √rooms/4 параллельными коридорами, соседние коридоры
+// связаны перемычками через каждые 10 комнат. На каждый коридор
+// приходится 10 муравьёв. Карты используются бенчмарками стадий
+// и командой "lem-in bench".
+func GenerateMap(rooms int) string {
+	w := max(2, int(math.Sqrt(float64(rooms))/4))
+	inner := max(rooms-2, w)
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d\n##start\ns 0 0\n##end\ne %d 0\n", 10*w, inner/w+2)
+
+	corridors := make([][]string, w)
+	for i := range corridors {
+		length := inner / w
+		if i < inner%w {
+			length++
+		}
+		for j := 0; j < length; j++ {
+			name := fmt.Sprintf("c%d_%d", i, j)
+			corridors[i] = append(corridors[i], name)
+			fmt.Fprintf(&b, "%s %d %d\n", name, j+1, i+1)
+		}
+	}
+	for i, c := range corridors {
+		fmt.Fprintf(&b, "s-%s\n", c[0])
+		for j := 1; j < len(c); j++ {
+			fmt.Fprintf(&b, "%s-%s\n", c[j-1], c[j])
+		}
+		fmt.Fprintf(&b, "%s-e\n", c[len(c)-1])
+		if i > 0 {
+			for j := 5; j < len(c) && j < len(corridors[i-1]); j += 10 {
+				fmt.Fprintf(&b, "%s-%s\n", corridors[i-1][j], c[j])
+			}
+		}
+	}
+	return b.String()
+}
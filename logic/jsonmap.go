@@ -0,0 +1,238 @@
+package logic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// JSONMap — описание карты в формате JSON. Классическая карта задаёт
+// start и end, карта с несколькими колониями — colonies и ends
+// (вместо ends можно указать единственный end):
+//
+//	{
+//	  "ants": 3,
+//	  "start": "s",
+//	  "end": "e",
+//	  "rooms": [
+//	    {"name": "s", "x": 0, "y": 0},
+//	    {"name": "hub", "x": 1, "y": 0, "capacity": 2, "tags": ["junction"]},
+//	    {"name": "e", "x": 2, "y": 0}
+//	  ],
+//	  "links": [
+//	    {"from": "s", "to": "hub"},
+//	    {"from": "hub", "to": "e", "weight": 3, "directed": true}
+//	  ]
+//	}
+type JSONMap struct {
+	Ants     int          `json:"ants"`
+	Start    string       `json:"start,omitempty"`
+	End      string       `json:"end,omitempty"`
+	Colonies []JSONColony `json:"colonies,omitempty"`
+	Ends     []string     `json:"ends,omitempty"`
+	Rooms    []JSONRoom   `json:"rooms"`
+	Links    []JSONLink   `json:"links"`
+}
+
+// JSONColony — колония: стартовая комната и число её муравьёв.
+type JSONColony struct {
+	Start string `json:"start"`
+	Ants  int    `json:"ants"`
+}
+
+// JSONRoom — комната; capacity 0 означает вместимость по умолчанию (1),
+// tags — произвольные метки, которые решатели не используют.
+type JSONRoom struct {
+	Name     string   `json:"name"`
+	X        int      `json:"x"`
+	Y        int      `json:"y"`
+	Capacity int      `json:"capacity,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// JSONLink — туннель; weight 0 означает вес 1, directed — туннель
+// только from→to.
+type JSONLink struct {
+	From     string `json:"from"`
+	To       string `json:"to"`
+	Weight   int    `json:"weight,omitempty"`
+	Directed bool   `json:"directed,omitempty"`
+}
+
+// ParseJSON разбирает карту в формате JSON (см. JSONMap) с теми же
+// проверками, что и текстовый парсер; ошибка указывает элемент,
+// например rooms[2]. Неизвестные поля — ошибка. Номеров строк у такой
+// карты нет, поэтому Source и Input остаются пустыми.
+func ParseJSON(data []byte) (*Graph, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var m JSONMap
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	return m.Graph()
+}
+
+// Graph строит граф по описанию m, проверяя его так же, как parseLines.
+func (m *JSONMap) Graph() (*Graph, error) {
+	g := &Graph{
+		Rooms:   make(map[string]*Room),
+		Links:   make(map[string][]string),
+		Weights: make(map[[2]string]int),
+	}
+	if m.Ants <= 0 {
+		return nil, fmt.Errorf("invalid number of ants: %d", m.Ants)
+	}
+	g.NumAnts = m.Ants
+
+	for i, r := range m.Rooms {
+		// В тексте имя — одно поле строки, поэтому пустое имя или имя
+		// с пробельными символами записать нельзя
+		if len(strings.Fields(r.Name)) != 1 || strings.TrimSpace(r.Name) != r.Name {
+			return nil, fmt.Errorf("invalid room name in rooms[%d]: %q", i, r.Name)
+		}
+		if err := checkRoomName(r.Name); err != nil {
+			return nil, fmt.Errorf("invalid room name in rooms[%d]: %v", i, err)
+		}
+		if _, ok := g.Rooms[r.Name]; ok {
+			return nil, fmt.Errorf("duplicate room %s in rooms[%d]", r.Name, i)
+		}
+		capacity := r.Capacity
+		if capacity == 0 {
+			capacity = 1
+		}
+		if capacity < 1 {
+			return nil, fmt.Errorf("invalid capacity %d in rooms[%d]", r.Capacity, i)
+		}
+		g.Rooms[r.Name] = &Room{Name: r.Name, X: r.X, Y: r.Y, Capacity: capacity, Tags: r.Tags}
+	}
+
+	tunnelRooms := 0
+	for i, l := range m.Links {
+		weight := l.Weight
+		if weight == 0 {
+			weight = 1
+		}
+		if weight < 1 {
+			return nil, fmt.Errorf("invalid link weight %d in links[%d]", l.Weight, i)
+		}
+		if weight > maxLinkWeight {
+			return nil, fmt.Errorf("link weight above %d in links[%d]", maxLinkWeight, i)
+		}
+		if l.From == l.To {
+			return nil, fmt.Errorf("invalid link, self-loop detected in links[%d]: %s", i, l.From)
+		}
+		for _, name := range []string{l.From, l.To} {
+			if _, ok := g.Rooms[name]; !ok {
+				return nil, fmt.Errorf("invalid link, room %s not found in links[%d]", name, i)
+			}
+		}
+		if tunnelRooms += weight - 1; tunnelRooms > maxTunnelRooms {
+			return nil, fmt.Errorf("weighted tunnels need more than %d rooms in links[%d]", maxTunnelRooms, i)
+		}
+		g.Links[l.From] = append(g.Links[l.From], l.To)
+		if weight != 1 {
+			g.Weights[[2]string{l.From, l.To}] = weight
+		}
+		if !l.Directed {
+			g.Links[l.To] = append(g.Links[l.To], l.From)
+			if weight != 1 {
+				g.Weights[[2]string{l.To, l.From}] = weight
+			}
+		}
+	}
+
+	ends := m.Ends
+	if m.End != "" {
+		if len(ends) > 0 {
+			return nil, fmt.Errorf("end and ends cannot be used together")
+		}
+		ends = []string{m.End}
+	}
+	var starts []string
+	var sizes []int
+	if len(m.Colonies) > 0 {
+		if m.Start != "" {
+			return nil, fmt.Errorf("start and colonies cannot be used together")
+		}
+		for i, c := range m.Colonies {
+			if c.Ants <= 0 {
+				return nil, fmt.Errorf("invalid colony size %d in colonies[%d]", c.Ants, i)
+			}
+			starts = append(starts, c.Start)
+			sizes = append(sizes, c.Ants)
+		}
+	} else if m.Start != "" {
+		starts = []string{m.Start}
+	}
+
+	if len(starts) == 0 || len(ends) == 0 {
+		return nil, fmt.Errorf("missing start or end")
+	}
+	if len(sizes) == 0 {
+		if len(ends) != 1 {
+			return nil, fmt.Errorf("exactly one start and one end room are required")
+		}
+	} else if err := setColonies(g, starts, ends, sizes, len(sizes)); err != nil {
+		return nil, err
+	}
+	g.Start, g.End = starts[0], ends[0]
+	for _, name := range starts {
+		if _, ok := g.Rooms[name]; !ok {
+			return nil, fmt.Errorf("start room not declared")
+		}
+	}
+	for _, name := range ends {
+		if _, ok := g.Rooms[name]; !ok {
+			return nil, fmt.Errorf("end room not declared")
+		}
+	}
+	for _, name := range append(starts, ends...) {
+		if g.Rooms[name].Capacity > 1 {
+			return nil, fmt.Errorf("start or end room %s cannot have a capacity", name)
+		}
+	}
+	return g, nil
+}
+
+// FormatJSON записывает карту g в формате JSON в том же порядке,
+// что и Lines: старты, финиши, остальные комнаты по имени, туннели
+// по концам. Туннели восстанавливаются по связям графа, как в Lines.
+func FormatJSON(g *Graph) ([]byte, error) {
+	m := JSONMap{Ants: g.NumAnts, Rooms: []JSONRoom{}, Links: []JSONLink{}}
+	var names []string
+	if len(g.Colonies) > 0 {
+		for _, c := range g.Colonies {
+			m.Colonies = append(m.Colonies, JSONColony{Start: c.Start, Ants: c.Ants})
+			names = append(names, c.Start)
+		}
+		m.Ends = append(m.Ends, g.sinks()...)
+	} else {
+		m.Start, m.End = g.Start, g.End
+		names = append(names, g.Start)
+	}
+	names = append(names, g.sinks()...)
+	names = append(names, g.innerRooms()...)
+	for _, name := range names {
+		r := g.Rooms[name]
+		room := JSONRoom{Name: name, X: r.X, Y: r.Y, Tags: r.Tags}
+		if r.Capacity > 1 {
+			room.Capacity = r.Capacity
+		}
+		m.Rooms = append(m.Rooms, room)
+	}
+	for _, decl := range sortDecls(adjacencyDecls(g)) {
+		key := linkKey(decl)
+		link := JSONLink{From: key[0], To: key[1], Directed: decl.Directed}
+		if decl.Weight > 1 {
+			link.Weight = decl.Weight
+		}
+		m.Links = append(m.Links, link)
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
@@ -0,0 +1,393 @@
+package logic
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LintWarning — замечание к корректной, но подозрительной карте.
+type LintWarning struct {
+	Check   string `json:"check"`          // вид проверки, например "dead-end"
+	Line    int    `json:"line,omitempty"` // строка ввода; 0 — если неизвестна
+	Message string `json:"message"`
+}
+
+// Lint проверяет разобранную карту и возвращает замечания по порядку строк:
+//
+//   - no-path — ни один финиш не достижим со старта;
+//   - isolated — связная компонента без старта и финиша;
+//   - unreachable — комната, недостижимая со старта по туннелям;
+//   - dead-end — достижимая комната, через которую не проходит ни один
+//     простой путь старт→финиш (для двусторонних туннелей проверка точная,
+//     однонаправленные учитываются только через достижимость);
+//   - duplicate-link — туннель объявлен повторно;
+//   - same-coordinates — у комнат совпадают X,Y (ломает визуализацию);
+//   - bottleneck — максимальный поток ограничен одной комнатой.
+func Lint(g *Graph) []LintWarning {
+	l := &linter{g: g}
+	l.components()
+	l.deadEnds()
+	l.duplicateLinks()
+	l.sameCoordinates()
+	l.bottleneck()
+	sort.SliceStable(l.warnings, func(i, j int) bool { return l.warnings[i].Line < l.warnings[j].Line })
+	return l.warnings
+}
+
+type linter struct {
+	g        *Graph
+	warnings []LintWarning
+	isolated map[string]bool // комнаты компонент без старта и финиша
+	noPath   bool
+}
+
+func (l *linter) warn(check string, line int, format string, args ...any) {
+	l.warnings = append(l.warnings, LintWarning{Check: check, Line: line, Message: fmt.Sprintf(format, args...)})
+}
+
+// roomLine возвращает строку объявления комнаты или 0.
+func (l *linter) roomLine(name string) int {
+	if l.g.Source == nil {
+		return 0
+	}
+	return l.g.Source.Rooms[name]
+}
+
+// sortedRooms возвращает имена комнат по строкам объявления (затем по имени).
+func (l *linter) sortedRooms() []string {
+	names := make([]string, 0, len(l.g.Rooms))
+	for name := range l.g.Rooms {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		li, lj := l.roomLine(names[i]), l.roomLine(names[j])
+		if li != lj {
+			return li < lj
+		}
+		return names[i] < names[j]
+	})
+	return names
+}
+
+// neighbours возвращает соседей комнаты без учёта направления туннелей.
+func (l *linter) neighbours() map[string][]string {
+	seen := make(map[[2]string]bool)
+	nb := make(map[string][]string)
+	for _, from := range l.sortedRooms() {
+		for _, to := range l.g.Links[from] {
+			for _, pair := range [][2]string{{from, to}, {to, from}} {
+				if !seen[pair] {
+					seen[pair] = true
+					nb[pair[0]] = append(nb[pair[0]], pair[1])
+				}
+			}
+		}
+	}
+	return nb
+}
+
+// components находит компоненты без терминальных комнат и комнаты,
+// недостижимые со старта.
+func (l *linter) components() {
+	nb := l.neighbours()
+	l.isolated = make(map[string]bool)
+	comp := make(map[string]int)
+	for _, name := range l.sortedRooms() {
+		if _, ok := comp[name]; ok {
+			continue
+		}
+		id := len(comp)
+		members := []string{name}
+		comp[name] = id
+		terminal := false
+		for i := 0; i < len(members); i++ {
+			terminal = terminal || l.g.isTerminal(members[i])
+			for _, next := range nb[members[i]] {
+				if _, ok := comp[next]; !ok {
+					comp[next] = id
+					members = append(members, next)
+				}
+			}
+		}
+		if terminal {
+			continue
+		}
+		for _, m := range members {
+			l.isolated[m] = true
+		}
+		l.warn("isolated", l.roomLine(name), "rooms %s form a component with no start or end", nameList(members))
+	}
+
+	reach := l.reachable(startNames(l.g), l.g.Links)
+	for _, name := range l.sortedRooms() {
+		if !reach[name] && !l.isolated[name] {
+			l.warn("unreachable", l.roomLine(name), "room %s is unreachable from the start", name)
+		}
+	}
+	ends := false
+	for _, end := range l.g.sinks() {
+		ends = ends || reach[end]
+	}
+	if !ends {
+		l.noPath = true
+		line := 0
+		if l.g.Source != nil {
+			line = l.g.Source.AntsLine
+		}
+		l.warn("no-path", line, "no end room is reachable from the start")
+	}
+}
+
+// reachable обходит туннели links в ширину от комнат from.
+func (l *linter) reachable(from []string, links map[string][]string) map[string]bool {
+	seen := make(map[string]bool)
+	queue := append([]string{}, from...)
+	for _, name := range from {
+		seen[name] = true
+	}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range links[cur] {
+			if !seen[next] {
+				seen[next] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	return seen
+}
+
+// deadEnds отмечает достижимые комнаты, не лежащие ни на одном
+// простом пути старт→финиш. Комната лежит на таком пути, если её блок
+// (компонента двусвязности) — на пути между стартом и финишем в дереве
+// блоков; кроме того, из неё должен достигаться финиш.
+func (l *linter) deadEnds() {
+	if l.noPath {
+		return
+	}
+	reach := l.reachable(startNames(l.g), l.g.Links)
+	reverse := make(map[string][]string)
+	for from, links := range l.g.Links {
+		for _, to := range links {
+			reverse[to] = append(reverse[to], from)
+		}
+	}
+	coreach := l.reachable(l.g.sinks(), reverse)
+	usable := l.onSimplePaths()
+	for _, name := range l.sortedRooms() {
+		if !reach[name] || l.g.isTerminal(name) {
+			continue
+		}
+		if !usable[name] || !coreach[name] {
+			l.warn("dead-end", l.roomLine(name), "room %s cannot be part of any start-end path", name)
+		}
+	}
+}
+
+// onSimplePaths возвращает комнаты, лежащие хотя бы на одном простом
+// пути между виртуальным истоком (соседом всех стартов) и виртуальным
+// стоком (соседом всех финишей) без учёта направления туннелей.
+func (l *linter) onSimplePaths() map[string]bool {
+	names := l.sortedRooms()
+	index := make(map[string]int, len(names))
+	for i, name := range names {
+		index[name] = i
+	}
+	src, dst := len(names), len(names)+1
+	adj := make([][]int, len(names)+2)
+	nb := l.neighbours()
+	for i, name := range names {
+		for _, next := range nb[name] {
+			adj[i] = append(adj[i], index[next])
+		}
+	}
+	link := func(a, b int) {
+		adj[a] = append(adj[a], b)
+		adj[b] = append(adj[b], a)
+	}
+	for _, name := range startNames(l.g) {
+		link(src, index[name])
+	}
+	for _, name := range l.g.sinks() {
+		link(dst, index[name])
+	}
+
+	blocks := biconnectedBlocks(adj, src)
+	// Дерево блоков: узлы 0..len(blocks)-1 — блоки, далее — точки сочленения
+	blocksOf := make([][]int, len(adj))
+	for b, block := range blocks {
+		for _, v := range block {
+			blocksOf[v] = append(blocksOf[v], b)
+		}
+	}
+	node := func(v int) int {
+		if len(blocksOf[v]) == 1 {
+			return blocksOf[v][0]
+		}
+		return len(blocks) + v
+	}
+	tree := make(map[int][]int)
+	for v, bs := range blocksOf {
+		if len(bs) > 1 {
+			for _, b := range bs {
+				tree[b] = append(tree[b], len(blocks)+v)
+				tree[len(blocks)+v] = append(tree[len(blocks)+v], b)
+			}
+		}
+	}
+	usable := make(map[string]bool)
+	if len(blocksOf[dst]) == 0 {
+		return usable
+	}
+	from, to := node(src), node(dst)
+	parent := map[int]int{from: from}
+	queue := []int{from}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		for _, next := range tree[cur] {
+			if _, ok := parent[next]; !ok {
+				parent[next] = cur
+				queue = append(queue, next)
+			}
+		}
+	}
+	for cur := to; ; cur = parent[cur] {
+		if cur < len(blocks) {
+			for _, v := range blocks[cur] {
+				if v < len(names) {
+					usable[names[v]] = true
+				}
+			}
+		}
+		if cur == from {
+			break
+		}
+	}
+	return usable
+}
+
+// biconnectedBlocks возвращает компоненты двусвязности (множества вершин),
+// достижимые из root. Обход итеративный, чтобы большие карты
+// не переполняли стек.
+func biconnectedBlocks(adj [][]int, root int) [][]int {
+	disc := make([]int, len(adj))
+	low := make([]int, len(adj))
+	for i := range disc {
+		disc[i] = -1
+	}
+	type frame struct{ v, parent, next int }
+	var blocks [][]int
+	var edges [][2]int
+	timer := 0
+	disc[root], low[root] = timer, timer
+	stack := []frame{{v: root, parent: -1}}
+	for len(stack) > 0 {
+		f := &stack[len(stack)-1]
+		if f.next < len(adj[f.v]) {
+			w := adj[f.v][f.next]
+			f.next++
+			if disc[w] < 0 {
+				timer++
+				disc[w], low[w] = timer, timer
+				edges = append(edges, [2]int{f.v, w})
+				stack = append(stack, frame{v: w, parent: f.v})
+			} else if w != f.parent && disc[w] < disc[f.v] {
+				edges = append(edges, [2]int{f.v, w})
+				low[f.v] = min(low[f.v], disc[w])
+			}
+			continue
+		}
+		stack = stack[:len(stack)-1]
+		if len(stack) == 0 {
+			break
+		}
+		u, w := stack[len(stack)-1].v, f.v
+		low[u] = min(low[u], low[w])
+		if low[w] >= disc[u] {
+			seen := make(map[int]bool)
+			var block []int
+			for {
+				e := edges[len(edges)-1]
+				edges = edges[:len(edges)-1]
+				for _, v := range e {
+					if !seen[v] {
+						seen[v] = true
+						block = append(block, v)
+					}
+				}
+				if e == [2]int{u, w} {
+					break
+				}
+			}
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// duplicateLinks сообщает о туннелях, объявленных повторно.
+func (l *linter) duplicateLinks() {
+	if l.g.Source == nil {
+		return
+	}
+	first := make(map[[3]string]int)
+	for _, decl := range l.g.Source.Links {
+		key := [3]string{"->", decl.From, decl.To}
+		if !decl.Directed {
+			key = [3]string{"-", min(decl.From, decl.To), max(decl.From, decl.To)}
+		}
+		if line, ok := first[key]; ok {
+			l.warn("duplicate-link", decl.Line, "link %s%s%s repeats line %d", decl.From, key[0], decl.To, line)
+			continue
+		}
+		first[key] = decl.Line
+	}
+}
+
+// sameCoordinates сообщает о комнатах с одинаковыми координатами.
+func (l *linter) sameCoordinates() {
+	first := make(map[[2]int]string)
+	for _, name := range l.sortedRooms() {
+		r := l.g.Rooms[name]
+		at := [2]int{r.X, r.Y}
+		if other, ok := first[at]; ok {
+			l.warn("same-coordinates", l.roomLine(name), "room %s has the same coordinates %d,%d as room %s", name, r.X, r.Y, other)
+			continue
+		}
+		first[at] = name
+	}
+}
+
+// bottleneck сообщает, что максимальный поток ограничен одной комнатой.
+func (l *linter) bottleneck() {
+	if l.noPath {
+		return
+	}
+	flow, cut, bounded := minVertexCut(l.g)
+	if !bounded || len(cut) != 1 {
+		return
+	}
+	l.warn("bottleneck", l.roomLine(cut[0]), "max flow is %d: every path passes through room %s (capacity %d)",
+		flow, cut[0], l.g.Capacity(cut[0]))
+}
+
+// startNames возвращает все стартовые комнаты.
+func startNames(g *Graph) []string {
+	var names []string
+	for _, c := range g.sources() {
+		names = append(names, c.Start)
+	}
+	return names
+}
+
+// nameList перечисляет имена через запятую, сокращая длинные списки.
+func nameList(names []string) string {
+	const limit = 5
+	if len(names) <= limit {
+		return strings.Join(names, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(names[:limit], ", "), len(names)-limit)
+}
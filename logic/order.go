@@ -0,0 +1,67 @@
+package logic
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+)
+
+// Order задаёт, как решатели разрешают равенства: в каком порядке
+// перебираются туннели комнаты при поиске путей и как нумеруются
+// выбранные пути. Муравьи выходят на пути в порядке их номеров, поэтому
+// порядок путей определяет и номера муравьёв на каждом пути.
+//
+// При любом порядке результат детерминирован: одна и та же карта
+// всегда даёт один и тот же вывод.
+type Order int
+
+const (
+	// OrderInput — туннели комнаты перебираются в порядке объявления
+	// во вводе, пути одной длины нумеруются в порядке нахождения
+	// (поведение по умолчанию). Перестановка строк ввода может
+	// изменить выбор между равноценными путями.
+	OrderInput Order = iota
+	// OrderName — туннели перебираются по имени соседней комнаты, пути
+	// одной длины нумеруются по именам комнат. Вывод не зависит
+	// от порядка строк ввода.
+	OrderName
+)
+
+// ParseOrder разбирает название порядка: "input" или "name".
+func ParseOrder(name string) (Order, error) {
+	switch name {
+	case "", "input":
+		return OrderInput, nil
+	case "name":
+		return OrderName, nil
+	}
+	return 0, fmt.Errorf("unknown order %q (want input or name)", name)
+}
+
+func (o Order) String() string {
+	if o == OrderName {
+		return "name"
+	}
+	return "input"
+}
+
+// sortLinks упорядочивает туннели каждой комнаты g согласно o.
+func (o Order) sortLinks(g *Graph) {
+	if o != OrderName {
+		return
+	}
+	for _, links := range g.Links {
+		sort.Strings(links)
+	}
+}
+
+// sortPaths нумерует пути: короче — раньше, при равной длине —
+// в порядке нахождения (OrderInput) или по именам комнат (OrderName).
+func (o Order) sortPaths(paths []Path) {
+	sort.SliceStable(paths, func(i, j int) bool {
+		if len(paths[i]) != len(paths[j]) {
+			return len(paths[i]) < len(paths[j])
+		}
+		return o == OrderName && slices.Compare(paths[i], paths[j]) < 0
+	})
+}
@@ -0,0 +1,187 @@
+package logic
+
+import (
+	"context"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// orderShuffles — сколько перестановок строк проверяется для каждой карты.
+const orderShuffles = 20
+
+// TestOrderShuffledInput решает каждый пример и его же с переставленными
+// строками: объявления комнат (вместе с командами ## перед ними)
+// и туннелей перемешиваются, а двусторонние туннели случайно
+// записываются в обратную сторону. Такая перестановка не меняет карту,
+// поэтому при OrderInput обязано совпасть число ходов, а при OrderName —
+// весь вывод. Перестановки задаются фиксированным seed.
+func TestOrderShuffledInput(t *testing.T) {
+	forEachExample(t, func(t *testing.T, path string, _ *Graph) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, order := range []Order{OrderInput, OrderName} {
+			t.Run(order.String(), func(t *testing.T) {
+				checkShuffles(t, string(data), Options{Order: order})
+			})
+		}
+	})
+	for name, input := range variantMaps {
+		for _, order := range []Order{OrderInput, OrderName} {
+			t.Run(name+"/"+order.String(), func(t *testing.T) {
+				checkShuffles(t, input, Options{Order: order})
+			})
+		}
+	}
+}
+
+// variantMaps — карты с тем, чего нет в примерах: веса (в том числе
+// комната с двоеточием в имени), направленные туннели, вместимость
+// комнат и несколько колоний.
+var variantMaps = map[string]string{
+	"weighted": `4
+##start
+s 0 0
+##end
+e 3 0
+a 1 0
+b 1 1
+q:1 2 1
+s-a:3
+a-e
+s-b:2
+b-q:1
+q:1-e
+`,
+	"directed": `3
+##start
+s 0 0
+##end
+e 3 0
+a 1 0
+b 1 1
+c 2 1
+s->a
+a->e
+s-b
+b->c
+c->e
+c-a
+`,
+	"capacity": `6
+##start
+s 0 0
+##end
+e 3 0
+##capacity 3
+a 1 0
+b 1 1
+c 2 1
+s-a
+a-e
+s-b
+b-c
+c-e
+`,
+	"colonies": twoColonies,
+}
+
+func checkShuffles(t *testing.T, input string, opts Options) {
+	orig, err := Parse(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Solve(context.Background(), orig, opts)
+	rng := rand.New(rand.NewPCG(1, 1))
+	for round := 1; round <= orderShuffles; round++ {
+		shuffled := shuffleMap(input, orig, rng)
+		g, err := Parse(shuffled)
+		if err != nil {
+			t.Fatalf("shuffle %d does not parse: %v", round, err)
+		}
+		got := Solve(context.Background(), g, opts)
+		switch {
+		case got.Error != want.Error:
+			t.Fatalf("shuffle %d: error %q, original %q", round, got.Error, want.Error)
+		case len(got.Output) != len(want.Output):
+			t.Fatalf("shuffle %d: %d turns, original %d", round, len(got.Output), len(want.Output))
+		case opts.Order == OrderName && !slices.Equal(got.Output, want.Output):
+			t.Fatalf("shuffle %d: moves differ from the original with order name", round)
+		}
+	}
+}
+
+// shuffleMap переставляет объявления карты. Строка с числом муравьёв
+// остаётся первой, комментарии отбрасываются, команды ## остаются
+// перед своей комнатой. g — та же карта, разобранная: по ней видно,
+// где у туннеля вес, а где двоеточие — часть имени комнаты.
+func shuffleMap(input string, g *Graph, rng *rand.Rand) string {
+	var head []string
+	var rooms [][]string
+	var links []string
+	var pending []string
+	for _, line := range strings.Split(strings.TrimSpace(input), "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, "##"):
+			pending = append(pending, line)
+		case strings.HasPrefix(line, "#"):
+		case len(head) == 0:
+			head = append(head, line)
+		case strings.Contains(line, " "):
+			rooms = append(rooms, append(pending, line))
+			pending = nil
+		default:
+			if a, b, ok := strings.Cut(line, "-"); ok && !strings.HasPrefix(b, ">") && rng.IntN(2) == 0 {
+				// "a-b:w" → "b-a:w"
+				to, weight := b, ""
+				if _, room := g.Rooms[b]; !room {
+					to, weight, _ = strings.Cut(b, ":")
+				}
+				line = to + "-" + a
+				if weight != "" {
+					line += ":" + weight
+				}
+			}
+			links = append(links, line)
+		}
+	}
+	// Колонии нумеруются в порядке объявления стартов, поэтому старты
+	// и финиши сохраняют взаимный порядок: занимают места, выпавшие
+	// терминальным комнатам при перестановке, по очереди.
+	var terminals [][]string
+	for _, block := range rooms {
+		if isTerminalBlock(block) {
+			terminals = append(terminals, block)
+		}
+	}
+	rng.Shuffle(len(rooms), func(i, j int) { rooms[i], rooms[j] = rooms[j], rooms[i] })
+	for i, block := range rooms {
+		if isTerminalBlock(block) {
+			rooms[i], terminals = terminals[0], terminals[1:]
+		}
+	}
+	rng.Shuffle(len(links), func(i, j int) { links[i], links[j] = links[j], links[i] })
+	lines := head
+	for _, block := range rooms {
+		lines = append(lines, block...)
+	}
+	lines = append(lines, links...)
+	lines = append(lines, pending...)
+	return strings.Join(lines, "\n")
+}
+
+// isTerminalBlock сообщает, что перед комнатой стоит ##start или ##end.
+func isTerminalBlock(block []string) bool {
+	for _, line := range block {
+		if strings.HasPrefix(line, "##start") || strings.HasPrefix(line, "##end") {
+			return true
+		}
+	}
+	return false
+}
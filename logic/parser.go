@@ -6,19 +6,57 @@ import (
 	"strings"
 )
 
+// Пределы весов туннелей: туннель веса w разворачивается в w-1
+// промежуточных комнат (см. expandWeightedLinks), поэтому и вес одного
+// туннеля, и общее число таких комнат на карте ограничены — иначе
+// короткий ввод вроде "s-e:1000000000" съел бы всю память.
+const (
+	maxLinkWeight  = 10000
+	maxTunnelRooms = 200000
+)
+
+// ParseError — ошибка в строке Line разбираемого текста (карты или файла
+// событий): Msg — что не так, Text — сама строка или уточнение.
+// Ошибки, не привязанные к строке (например, нет старта), — обычные.
+type ParseError struct {
+	Line int
+	Msg  string
+	Text string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s at line %d: %s", e.Msg, e.Line, e.Text)
+}
+
 // parseLines парсит входные строки в структуру Graph.
-// Поддерживаются комментарии, директивы \"##start\"/\"##end\",
-// декларации комнат и рёбер. Валидирует формат и обязательные сущности.
+// Поддерживаются комментарии, директивы \"##start\"/\"##end\"
+// (\"##start N\" включает режим нескольких колоний), декларации комнат
+// (с необязательной директивой \"##capacity N\" перед ними) и рёбер
+// (в том числе с весом \"a-b:3\" и однонаправленных \"a->b\").
+// Валидирует формат и обязательные сущности.
 func parseLines(lines []string) (*Graph, error) {
 	g := &Graph{
-		Rooms: make(map[string]*Room),
-		Links: make(map[string][]string),
-		Input: lines,
+		Rooms:   make(map[string]*Room),
+		Links:   make(map[string][]string),
+		Weights: make(map[[2]string]int),
+		Input:   lines,
+		Source:  &Source{Rooms: make(map[string]int)},
 	}
 	antsParsed := false
 	parsingRooms := true
 	countstart := 0
 	countend := 0
+	pendingCapacity := 0     // вместимость из ##capacity, ждущая комнату
+	capacityLine := 0        // строка этой директивы
+	pendingTerminal := false // был ##start/##end, комнаты ещё нет
+	tunnelRooms := 0
+	var colonySizes []int
+
+	// ##capacity относится к следующей строке-комнате, и это должна быть
+	// промежуточная комната: вместимость старта и финиша не ограничена
+	misplacedCapacity := func(i int, line string) error {
+		return &ParseError{Line: i + 1, Msg: fmt.Sprintf("##capacity at line %d must be followed by an intermediate room", capacityLine), Text: line}
+	}
 
 	for i, line := range lines {
 		line = strings.TrimSpace(line)
@@ -28,49 +66,120 @@ func parseLines(lines []string) (*Graph, error) {
 			continue
 		}
 
-		// Обрабатываем специальные команды ##start и ##end
-		if line == "##start" || line == "##end" {
-			if line == "##start" {
+		// Обрабатываем специальные команды ##start и ##end;
+		// "##start N" объявляет колонию из N муравьёв
+		if fields := strings.Fields(line); fields[0] == "##start" || fields[0] == "##end" {
+			if pendingCapacity > 0 {
+				return nil, misplacedCapacity(i, line)
+			}
+			pendingTerminal = true
+			if len(fields) == 2 && fields[0] == "##start" {
+				n, err := strconv.Atoi(fields[1])
+				if err != nil || n <= 0 {
+					return nil, &ParseError{Line: i + 1, Msg: "invalid colony size", Text: line}
+				}
+				colonySizes = append(colonySizes, n)
+			} else if len(fields) != 1 {
+				return nil, &ParseError{Line: i + 1, Msg: "invalid command", Text: line}
+			}
+			if fields[0] == "##start" {
 				countstart++
-			} else if line == "##end" {
+			} else {
 				countend++
 			}
 			continue
 		}
 
+		// Директива ##capacity N задаёт вместимость следующей комнаты
+		if fields := strings.Fields(line); fields[0] == "##capacity" {
+			if pendingTerminal {
+				return nil, &ParseError{Line: i + 1, Msg: "start or end room cannot have a capacity", Text: line}
+			}
+			if pendingCapacity > 0 {
+				return nil, misplacedCapacity(i, line)
+			}
+			if len(fields) != 2 {
+				return nil, &ParseError{Line: i + 1, Msg: "invalid capacity directive", Text: line}
+			}
+			n, err := strconv.Atoi(fields[1])
+			if err != nil || n < 1 {
+				return nil, &ParseError{Line: i + 1, Msg: "invalid capacity directive", Text: line}
+			}
+			pendingCapacity, capacityLine = n, i+1
+			continue
+		}
+
 		// Пропускаем обычные комментарии (начинающиеся с #, но не ##start/##end)
 		if strings.HasPrefix(line, "#") {
 			continue
 		}
 
+		if pendingCapacity > 0 && (!antsParsed || !parsingRooms || strings.Contains(line, "-") || len(strings.Fields(line)) != 3) {
+			return nil, misplacedCapacity(i, line)
+		}
+
 		if !antsParsed {
 			n, err := strconv.Atoi(line)
 			if err != nil || n <= 0 {
-				return nil, fmt.Errorf("invalid number of ants at line %d: %s", i+1, line)
+				return nil, &ParseError{Line: i + 1, Msg: "invalid number of ants", Text: line}
 			}
 			g.NumAnts = n
+			g.Source.AntsLine = i + 1
 			antsParsed = true
 			continue
 		}
 
 		if strings.Contains(line, "-") {
 			parsingRooms = false
-			parts := strings.Split(line, "-")
+			// Однонаправленный туннель записывается как "a->b"
+			sep := "-"
+			directed := strings.Contains(line, "->")
+			if directed {
+				sep = "->"
+			}
+			parts := strings.Split(line, sep)
 			if len(parts) != 2 {
-				return nil, fmt.Errorf("invalid link format at line %d: %s", i+1, line)
+				return nil, &ParseError{Line: i + 1, Msg: "invalid link format", Text: line}
 			}
 			a, b := strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1])
+			// Необязательный вес туннеля: "a-b:3" — проход занимает 3 хода.
+			// Если комната с таким именем уже есть, суффикс не разбираем.
+			weight := 1
+			if _, exists := g.Rooms[b]; !exists {
+				if name, w, ok := strings.Cut(b, ":"); ok {
+					n, err := strconv.Atoi(strings.TrimSpace(w))
+					if err != nil || n < 1 {
+						return nil, &ParseError{Line: i + 1, Msg: "invalid link weight", Text: line}
+					}
+					if n > maxLinkWeight {
+						return nil, &ParseError{Line: i + 1, Msg: fmt.Sprintf("link weight above %d", maxLinkWeight), Text: line}
+					}
+					b, weight = strings.TrimSpace(name), n
+				}
+			}
 			if a == b {
-				return nil, fmt.Errorf("invalid link format, self-loop detected at line %d: %s", i+1, line)
+				return nil, &ParseError{Line: i + 1, Msg: "invalid link format, self-loop detected", Text: line}
 			}
 			if _, okA := g.Rooms[a]; !okA {
-				return nil, fmt.Errorf("invalid link format, room %s not found at line %d: %s", a, i+1, line)
+				return nil, &ParseError{Line: i + 1, Msg: fmt.Sprintf("invalid link format, room %s not found", a), Text: line}
 			}
 			if _, okB := g.Rooms[b]; !okB {
-				return nil, fmt.Errorf("invalid link format, room %s not found at line %d: %s", b, i+1, line)
+				return nil, &ParseError{Line: i + 1, Msg: fmt.Sprintf("invalid link format, room %s not found", b), Text: line}
 			}
+			if tunnelRooms += weight - 1; tunnelRooms > maxTunnelRooms {
+				return nil, &ParseError{Line: i + 1, Msg: fmt.Sprintf("weighted tunnels need more than %d rooms", maxTunnelRooms), Text: line}
+			}
+			g.Source.Links = append(g.Source.Links, LinkDecl{From: a, To: b, Directed: directed, Weight: weight, Line: i + 1})
 			g.Links[a] = append(g.Links[a], b)
-			g.Links[b] = append(g.Links[b], a)
+			if weight != 1 {
+				g.Weights[[2]string{a, b}] = weight
+			}
+			if !directed {
+				g.Links[b] = append(g.Links[b], a)
+				if weight != 1 {
+					g.Weights[[2]string{b, a}] = weight
+				}
+			}
 		} else if parsingRooms {
 			fields := strings.Fields(line)
 			if len(fields) != 3 {
@@ -81,57 +190,115 @@ func parseLines(lines []string) (*Graph, error) {
 			name := fields[0]
 
 			// ВАЖНО: Проверяем имя комнаты ПЕРЕД парсингом координат
-			if strings.HasPrefix(name, "L") {
-				return nil, fmt.Errorf("invalid room name at line %d: room name cannot start with 'L': %s", i+1, name)
-			}
-			if strings.HasPrefix(name, "#") {
-				return nil, fmt.Errorf("invalid room name at line %d: room name cannot start with '#': %s", i+1, name)
-			}
-			if strings.Contains(name, " ") {
-				return nil, fmt.Errorf("invalid room name at line %d: room name cannot contain spaces: %s", i+1, name)
+			if err := checkRoomName(name); err != nil {
+				return nil, &ParseError{Line: i + 1, Msg: "invalid room name", Text: err.Error()}
 			}
 
 			x, err1 := strconv.Atoi(fields[1])
 			y, err2 := strconv.Atoi(fields[2])
 			if err1 != nil || err2 != nil {
-				return nil, fmt.Errorf("invalid room coordinates at line %d: %s", i+1, line)
+				return nil, &ParseError{Line: i + 1, Msg: "invalid room coordinates", Text: line}
+			}
+			capacity := 1
+			if pendingCapacity > 0 {
+				capacity, pendingCapacity = pendingCapacity, 0
 			}
-			g.Rooms[name] = &Room{Name: name, X: x, Y: y}
+			g.Rooms[name] = &Room{Name: name, X: x, Y: y, Capacity: capacity}
+			g.Source.Rooms[name] = i + 1
+			pendingTerminal = false
 		}
 	}
+	if pendingCapacity > 0 {
+		return nil, &ParseError{Line: capacityLine, Msg: "##capacity must be followed by an intermediate room", Text: strings.TrimSpace(lines[capacityLine-1])}
+	}
 
-	foundStart, foundEnd := false, false
+	var starts, ends []string
 	for i, line := range lines {
-		line = strings.TrimSpace(line)
-		if line == "##start" && i+1 < len(lines) {
-			nxt := strings.TrimSpace(lines[i+1])
-			if !strings.HasPrefix(nxt, "#") && strings.Contains(nxt, " ") {
-				g.Start = strings.Fields(nxt)[0]
-				foundStart = true
-			}
-		} else if line == "##end" && i+1 < len(lines) {
-			nxt := strings.TrimSpace(lines[i+1])
-			if !strings.HasPrefix(nxt, "#") && strings.Contains(nxt, " ") {
-				g.End = strings.Fields(nxt)[0]
-				foundEnd = true
-			}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || i+1 >= len(lines) {
+			continue
+		}
+		nxt := strings.TrimSpace(lines[i+1])
+		if strings.HasPrefix(nxt, "#") || !strings.Contains(nxt, " ") {
+			continue
+		}
+		switch fields[0] {
+		case "##start":
+			starts = append(starts, strings.Fields(nxt)[0])
+		case "##end":
+			ends = append(ends, strings.Fields(nxt)[0])
 		}
 	}
 
 	if !antsParsed {
 		return nil, fmt.Errorf("missing number of ants")
 	}
-	if !foundStart || !foundEnd {
+	if len(starts) == 0 || len(ends) == 0 {
 		return nil, fmt.Errorf("missing start or end")
 	}
-	if countstart != 1 || countend != 1 {
-		return nil, fmt.Errorf("exactly one start and one end room are required")
+	if len(colonySizes) == 0 {
+		if countstart != 1 || countend != 1 {
+			return nil, fmt.Errorf("exactly one start and one end room are required")
+		}
+	} else if err := setColonies(g, starts, ends, colonySizes, countstart); err != nil {
+		return nil, err
 	}
-	if _, ok := g.Rooms[g.Start]; !ok {
-		return nil, fmt.Errorf("start room not declared")
+	g.Start, g.End = starts[0], ends[0]
+	for _, name := range starts {
+		if _, ok := g.Rooms[name]; !ok {
+			return nil, fmt.Errorf("start room not declared")
+		}
 	}
-	if _, ok := g.Rooms[g.End]; !ok {
-		return nil, fmt.Errorf("end room not declared")
+	for _, name := range ends {
+		if _, ok := g.Rooms[name]; !ok {
+			return nil, fmt.Errorf("end room not declared")
+		}
 	}
 	return g, nil
 }
+
+// checkRoomName проверяет, что имя комнаты можно записать в карту:
+// оно не похоже на ход муравья, комментарий или туннель.
+func checkRoomName(name string) error {
+	switch {
+	case strings.HasPrefix(name, "L"):
+		return fmt.Errorf("room name cannot start with 'L': %s", name)
+	case strings.HasPrefix(name, "#"):
+		return fmt.Errorf("room name cannot start with '#': %s", name)
+	case strings.Contains(name, " "):
+		return fmt.Errorf("room name cannot contain spaces: %s", name)
+	case strings.Contains(name, "-"):
+		return fmt.Errorf("room name cannot contain '-': %s", name)
+	}
+	return nil
+}
+
+// setColonies проверяет и сохраняет описание режима нескольких колоний:
+// у каждого старта должен быть размер колонии, сумма размеров равна
+// общему числу муравьёв, ни одна комната не может быть и стартом, и финишем.
+func setColonies(g *Graph, starts, ends []string, sizes []int, countstart int) error {
+	if countstart != len(sizes) || len(starts) != len(sizes) {
+		return fmt.Errorf("every start room needs a colony size in multi-colony mode")
+	}
+	total := 0
+	seen := make(map[string]bool)
+	for i, name := range starts {
+		if seen[name] {
+			return fmt.Errorf("duplicate start room: %s", name)
+		}
+		seen[name] = true
+		total += sizes[i]
+		g.Colonies = append(g.Colonies, Colony{Start: name, Ants: sizes[i]})
+	}
+	if total != g.NumAnts {
+		return fmt.Errorf("colony sizes sum to %d, expected %d ants", total, g.NumAnts)
+	}
+	for _, name := range ends {
+		if seen[name] {
+			return fmt.Errorf("room %s is both a start and an end", name)
+		}
+		seen[name] = true
+		g.Ends = append(g.Ends, name)
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package logic
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// forEachExample запускает check подтестом на каждом примере
+// test_case/example*.txt; path — путь к файлу, g — разобранная карта.
+func forEachExample(t *testing.T, check func(t *testing.T, path string, g *Graph)) {
+	t.Helper()
+	files, err := filepath.Glob("../test_case/example*.txt")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("no examples found: %v", err)
+	}
+	for _, path := range files {
+		t.Run(filepath.Base(path), func(t *testing.T) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			g, err := Parse(string(data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			check(t, path, g)
+		})
+	}
+}
+
+func TestParseErrorLine(t *testing.T) {
+	_, err := Parse("3\n##start\ns 0 0\n##end\ne 1 0\ns-x\n")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 6 {
+		t.Fatalf("got %v, want a ParseError at line 6", err)
+	}
+}
+
+func TestParseCapacity(t *testing.T) {
+	g, err := Parse("3\n##start\ns 0 0\n##end\ne 2 0\n# hub\n##capacity 2\nm 1 0\n##capacityX 5\nn 1 1\ns-m\nm-e\ns-n\nn-e\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Capacity("m") != 2 || g.Capacity("n") != 1 {
+		t.Errorf("capacity of m %d, of n %d; want 2 and 1 (##capacityX is a comment)", g.Capacity("m"), g.Capacity("n"))
+	}
+
+	head := "3\n##start\ns 0 0\n##end\ne 2 0\n"
+	for _, tc := range []struct {
+		name, input string
+		line        int
+	}{
+		{"before links", head + "m 1 0\n##capacity 2\ns-m\nm-e\n", 8},
+		{"before an invalid room", head + "##capacity 2\nm 1\ns-e\n", 7},
+		{"before start", "3\n##capacity 2\n##start\ns 0 0\n##end\ne 2 0\ns-e\n", 3},
+		{"after end", "3\n##start\ns 0 0\n##end\n##capacity 2\ne 2 0\ns-e\n", 5},
+		{"twice", head + "##capacity 2\n##capacity 3\nm 1 0\ns-m\nm-e\n", 7},
+		{"before ants", "##capacity 2\n3\n##start\ns 0 0\n##end\ne 2 0\ns-e\n", 2},
+		{"at the end", head + "s-e\n##capacity 2\n", 7},
+	} {
+		_, err := Parse(tc.input)
+		var parseErr *ParseError
+		if !errors.As(err, &parseErr) || parseErr.Line != tc.line {
+			t.Errorf("%s: got %v, want a ParseError at line %d", tc.name, err, tc.line)
+		}
+	}
+}
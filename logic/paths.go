@@ -1,63 +1,66 @@
 package logic
 
 import (
+	"context"
 	"sort"
 )
 
 // searchShortPath находит кратчайший путь (по количеству рёбер)
-// с использованием упрощённой идеи алгоритма Суурбалле.
-func searchShortPath(g *Graph) (Path, bool) {
+// с использованием упрощённой идеи алгоритма Суурбалле. left — оставшаяся
+// вместимость комнат: комнаты с left <= 0 (кроме финиша) закрыты.
+// Найденный путь удаляет свои туннели из g.Links и уменьшает left
+// для своих промежуточных комнат, так что повторные вызовы дают
+// непересекающиеся пути. Состояние поиска хранится локально, поэтому
+// на разных графах вызовы независимы.
+func searchShortPath(g *Graph, left map[string]int) (Path, bool) {
 	q := &sortedQueue{}
 	startRoom := g.Rooms[g.Start]
-	endRoom := g.Rooms[g.End]
-	for _, room := range g.Rooms {
-		room.Visit = false
-		room.Parent = nil
-		room.Weight = 0
-	}
-	startRoom.Visit = true
+	visit := map[string]bool{g.Start: true}
+	parent := make(map[string]string)
+	weights := make(map[string]int)
 	q.Enqueue(startRoom, 0)
 
-	for len(q.items) > 0 && !endRoom.Visit {
+	for len(q.items) > 0 && !visit[g.End] {
 		current := q.Dequeue()
 		currentRoom := current.Room
 		for _, nextName := range g.Links[currentRoom.Name] {
 			next := g.Rooms[nextName]
 			weight := 1
-			if !next.Visit {
-				if next.Separated && next != endRoom {
+			if !visit[nextName] {
+				if left[nextName] <= 0 && nextName != g.End {
 					continue
 				}
-				next.Visit = true
-				next.Parent = currentRoom
-				next.Weight = current.Weight + weight
-				q.Enqueue(next, next.Weight)
-			} else if current.Weight+weight < next.Weight {
-				next.Parent = currentRoom
-				next.Weight = current.Weight + weight
-				q.Enqueue(next, next.Weight)
+				visit[nextName] = true
+				parent[nextName] = currentRoom.Name
+				weights[nextName] = current.Weight + weight
+				q.Enqueue(next, weights[nextName])
+			} else if current.Weight+weight < weights[nextName] {
+				parent[nextName] = currentRoom.Name
+				weights[nextName] = current.Weight + weight
+				q.Enqueue(next, weights[nextName])
 			}
 		}
 	}
 
-	if !endRoom.Visit {
+	if !visit[g.End] {
 		return nil, false
 	}
 
 	path := []string{}
-	r := endRoom
-	for r != startRoom {
-		path = append([]string{r.Name}, path...)
-		r = r.Parent
+	r := g.End
+	for r != g.Start {
+		path = append([]string{r}, path...)
+		r = parent[r]
 	}
-	path = append([]string{startRoom.Name}, path...)
+	path = append([]string{g.Start}, path...)
 
 	for i := 0; i < len(path)-1; i++ {
 		from, to := path[i], path[i+1]
 		g.Links[from] = removeLink(g.Links[from], to)
 		g.Links[to] = removeLink(g.Links[to], from)
 		if i > 0 && i < len(path)-1 {
-			g.Rooms[path[i]].Separated = true
+			// Комната закрывается для новых путей, когда исчерпана её вместимость
+			left[path[i]]--
 		}
 	}
 
@@ -77,15 +80,18 @@ func removeLink(links []string, target string) []string {
 // с помощью последовательного применения searchShortPath.
 func findDisjointPaths(g *Graph) []Path {
 	var paths []Path
+	// Комнаты только читаются; туннели найденных путей удаляются из копии связей
 	gCopy := &Graph{
-		Rooms:   make(map[string]*Room),
+		Rooms:   g.Rooms,
 		Links:   make(map[string][]string),
 		Start:   g.Start,
 		End:     g.End,
 		NumAnts: g.NumAnts,
+		Rules:   g.Rules,
 	}
-	for name, room := range g.Rooms {
-		gCopy.Rooms[name] = &Room{Name: room.Name, X: room.X, Y: room.Y}
+	left := make(map[string]int, len(g.Rooms))
+	for name := range g.Rooms {
+		left[name] = g.Capacity(name)
 	}
 	for name, links := range g.Links {
 		gCopy.Links[name] = make([]string, len(links))
@@ -93,7 +99,7 @@ func findDisjointPaths(g *Graph) []Path {
 	}
 
 	for {
-		path, found := searchShortPath(gCopy)
+		path, found := searchShortPath(gCopy, left)
 		if !found {
 			break
 		}
@@ -103,14 +109,18 @@ func findDisjointPaths(g *Graph) []Path {
 }
 
 // dfsPaths собирает все простые пути от старта к финишу (DFS),
-// затем сортирует их по длине по возрастанию.
-func dfsPaths(g *Graph) []Path {
+// затем сортирует их по длине по возрастанию. При отмене ctx
+// перебор останавливается, и возвращаются уже найденные пути.
+func dfsPaths(ctx context.Context, g *Graph) []Path {
 	start, end := g.Start, g.End
 	visited := make(map[string]bool)
 	var current Path
 	var all []Path
 	var dfs func(string)
 	dfs = func(node string) {
+		if ctx.Err() != nil {
+			return
+		}
 		if node == end {
 			tmp := make(Path, len(current)+1)
 			copy(tmp, current)
@@ -135,53 +145,216 @@ func dfsPaths(g *Graph) []Path {
 	return all
 }
 
-// choosePathsDFS перебирает комбинации путей и выбирает набор,
-// минимизирующий число ходов (turns) при заданном числе муравьёв.
-func choosePathsDFS(paths []Path, ants int) []Path {
+// choosePathsDFS ищет среди путей (отсортированных по длине) набор
+// совместимых путей, минимизирующий число ходов (turns).
+// Перебор идёт с возвратом по графу конфликтов (пути, делящие комнату
+// вместимости 1, несовместимы; загрузка комнат большей вместимости
+// считается отдельно) и отсекает ветви нижней оценкой на основе distribute.
+// При отмене ctx возвращается лучший набор, найденный к этому моменту.
+func choosePathsDFS(ctx context.Context, g *Graph, paths []Path, ants int) []Path {
 	if len(paths) == 0 {
 		return nil
 	}
-	best := []Path{paths[0]}
-	bestTime := calcTime(best, ants)
-	n := len(paths)
-	for mask := 1; mask < (1 << n); mask++ {
-		var comb []Path
-		used := make(map[string]bool)
-		valid := true
-		for i := 0; i < n; i++ {
-			if mask&(1<<i) == 0 {
+	sorted := make([]Path, len(paths))
+	copy(sorted, paths)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) < len(sorted[j]) })
+
+	n := len(sorted)
+	conflicts, shared := buildConflicts(g, sorted)
+	load := make(map[string]int)
+	// Совместимых путей не больше, чем суммарная вместимость первых
+	// (и последних) промежуточных комнат среди кандидатов.
+	maxPaths := min(n, capacityAt(g, sorted, 1), capacityAt(g, sorted, -2))
+
+	lengths, rates := pathRates(g, sorted, ants)
+	best := []int{0}
+	_, bestTime := distribute(lengths[:1], rates[:1], ants)
+	tr := traceFrom(ctx)
+	evaluated, pruned, overloaded := 1, 0, 0
+	tr.add("branch and bound over %d candidates (at most %d compatible):", n, maxPaths)
+	tr.candidateSet(best, bestTime, true, evaluated)
+	var chosen []int
+	var chosenLens, chosenRates []int
+
+	var search func(next int, blocked bitset)
+	search = func(next int, blocked bitset) {
+		for i := next; i < n; i++ {
+			if ctx.Err() != nil {
+				return
+			}
+			if blocked.has(i) {
 				continue
 			}
-			p := paths[i]
-			for j := 1; j < len(p)-1; j++ {
-				if used[p[j]] {
-					valid = false
-					break
-				}
+			l := lengths[i]
+			// Все оставшиеся кандидаты не короче l, поэтому лучшее, что можно
+			// получить, — добавить kMax путей длины l (с пропускной способностью
+			// пути i, не меньшей, чем у следующих). Новый путь никогда
+			// не увеличивает число ходов, так что это нижняя оценка ветви.
+			kMax := min(blocked.countFree(i, n), maxPaths-len(chosen))
+			if kMax <= 0 {
+				return
 			}
-			if !valid {
-				break
+			boundLens, boundRates := append([]int{}, chosenLens...), append([]int{}, chosenRates...)
+			for k := 0; k < kMax; k++ {
+				boundLens = append(boundLens, l)
+				boundRates = append(boundRates, rates[i])
 			}
-			for j := 1; j < len(p)-1; j++ {
-				used[p[j]] = true
+			if _, lb := distribute(boundLens, boundRates, ants); lb >= bestTime {
+				pruned++
+				return
 			}
-			comb = append(comb, p)
-		}
-		if valid && len(comb) > 0 {
-			t := calcTime(comb, ants)
-			if t < bestTime {
+
+			if !fitsLoad(g, shared[i], load) {
+				overloaded++
+				continue
+			}
+
+			for _, room := range shared[i] {
+				load[room]++
+			}
+			chosen = append(chosen, i)
+			chosenLens = append(chosenLens, l)
+			chosenRates = append(chosenRates, rates[i])
+			_, t := distribute(chosenLens, chosenRates, ants)
+			improved := t < bestTime
+			if improved {
 				bestTime = t
-				best = comb
+				best = append(best[:0], chosen...)
+			}
+			// Набор из одного кратчайшего пути уже оценён до перебора
+			if len(chosen) > 1 || i > 0 {
+				evaluated++
+				tr.candidateSet(chosen, t, improved, evaluated)
+			}
+			search(i+1, blocked.or(conflicts[i]))
+			chosen = chosen[:len(chosen)-1]
+			chosenLens = chosenLens[:len(chosenLens)-1]
+			chosenRates = chosenRates[:len(chosenRates)-1]
+			for _, room := range shared[i] {
+				load[room]--
+			}
+		}
+	}
+	search(0, newBitset(n))
+	tr.add("  %d sets evaluated, %d branches pruned by the lower bound, %d paths skipped for room capacity",
+		evaluated, pruned, overloaded)
+
+	result := make([]Path, len(best))
+	for i, idx := range best {
+		result[i] = sorted[idx]
+	}
+	return result
+}
+
+// buildConflicts строит граф конфликтов: i и j конфликтуют, если пути
+// делят промежуточную комнату вместимости 1 или (если правила ограничивают
+// туннели) общий туннель. Комнаты большей вместимости возвращаются
+// отдельно для каждого пути (shared) — их загрузку проверяет fitsLoad.
+func buildConflicts(g *Graph, paths []Path) (conflicts []bitset, shared [][]string) {
+	owners := make(map[string][]int)
+	shared = make([][]string, len(paths))
+	for i, p := range paths {
+		for j := 1; j < len(p)-1; j++ {
+			if g.Capacity(p[j]) > 1 {
+				shared[i] = append(shared[i], p[j])
+				continue
+			}
+			owners[p[j]] = append(owners[p[j]], i)
+		}
+		if g.Rules.limitsTunnels() {
+			for j := 0; j < len(p)-1; j++ {
+				a, b := p[j], p[j+1]
+				if a > b {
+					a, b = b, a
+				}
+				key := a + "-" + b
+				owners[key] = append(owners[key], i)
 			}
 		}
 	}
-	return best
+	conflicts = make([]bitset, len(paths))
+	for i := range conflicts {
+		conflicts[i] = newBitset(len(paths))
+	}
+	for _, ids := range owners {
+		for _, a := range ids {
+			for _, b := range ids {
+				conflicts[a].set(b)
+			}
+		}
+	}
+	return conflicts, shared
+}
+
+// fitsLoad проверяет, что путь можно добавить, не превысив
+// вместимость общих комнат при текущей загрузке.
+func fitsLoad(g *Graph, rooms []string, load map[string]int) bool {
+	for _, room := range rooms {
+		if load[room] >= g.Capacity(room) {
+			return false
+		}
+	}
+	return true
+}
+
+// capacityAt суммирует вместимость различных комнат на позиции pos
+// в путях (отрицательная позиция отсчитывается от конца пути).
+// Старт и финиш на этой позиции считаются вместимостью 1: через прямой
+// туннель проходит один путь.
+func capacityAt(g *Graph, paths []Path, pos int) int {
+	seen := make(map[string]bool)
+	total := 0
+	for _, p := range paths {
+		i := pos
+		if i < 0 {
+			i += len(p)
+		}
+		if seen[p[i]] {
+			continue
+		}
+		seen[p[i]] = true
+		if p[i] == g.Start || p[i] == g.End {
+			total++
+		} else {
+			total += g.Capacity(p[i])
+		}
+	}
+	return total
+}
+
+// bitset — множество индексов путей фиксированного размера.
+type bitset []uint64
+
+func newBitset(n int) bitset { return make(bitset, (n+63)/64) }
+
+func (b bitset) has(i int) bool { return b[i/64]&(1<<(i%64)) != 0 }
+
+func (b bitset) set(i int) { b[i/64] |= 1 << (i % 64) }
+
+// or возвращает новое множество — объединение b и o.
+func (b bitset) or(o bitset) bitset {
+	r := make(bitset, len(b))
+	for i := range b {
+		r[i] = b[i] | o[i]
+	}
+	return r
+}
+
+// countFree считает индексы из [from, to), не входящие в множество.
+func (b bitset) countFree(from, to int) int {
+	free := 0
+	for i := from; i < to; i++ {
+		if !b.has(i) {
+			free++
+		}
+	}
+	return free
 }
 
 // choosePathsHybrid выбирает стратегию: для сложных графов
 // пытается Суурбалле, в противном случае — DFS, затем ищет
 // оптимальную комбинацию путей.
-func choosePathsHybrid(g *Graph, ants int) []Path {
+func choosePathsHybrid(ctx context.Context, g *Graph, ants int) []Path {
 	// Простая стратегия: для небольших входов предпочитаем DFS,
 	// для крупных/плотных графов или большого числа муравьёв — Суурбалле.
 	linkCount := 0
@@ -189,18 +362,27 @@ func choosePathsHybrid(g *Graph, ants int) []Path {
 		linkCount += len(links)
 	}
 	complexity := float64(linkCount) / float64(len(g.Rooms))
+	tr := traceFrom(ctx)
 
 	if ants > 100 || len(g.Rooms) > 30 || complexity > 2.5 {
+		tr.strategy(ants, len(g.Rooms), complexity, true)
 		paths := findDisjointPaths(g)
 		if len(paths) > 0 {
+			tr.paths("disjoint paths found", paths)
 			return paths
 		}
+		tr.add("findDisjointPaths found no paths, falling back to DFS")
+	} else {
+		tr.strategy(ants, len(g.Rooms), complexity, false)
 	}
 
 	// По умолчанию — DFS со стабильной сортировкой и выбором лучшей комбинации
-	paths := dfsPaths(g)
+	paths := dfsPaths(ctx, g)
+	tr.paths("candidate paths (simple paths, by length)", paths)
 	if len(paths) == 0 {
 		return nil
 	}
-	return choosePathsDFS(paths, ants)
+	chosen := choosePathsDFS(ctx, g, paths, ants)
+	tr.paths("chosen paths", chosen)
+	return chosen
 }
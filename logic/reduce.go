@@ -0,0 +1,204 @@
+package logic
+
+import (
+	"context"
+	"sort"
+)
+
+// Reduce уменьшает карту g, пока на ней воспроизводится сбой (fails
+// возвращает true): по очереди удаляет промежуточные комнаты, туннели
+// и муравьёв, повторяя проходы, пока карта уменьшается. Комнаты и туннели
+// отбрасываются алгоритмом ddmin — сначала большими группами, затем
+// всё мельче. fails получает независимую копию карты и может её менять.
+//
+// Граф g не изменяется. Если fails не выполняется на самой g,
+// возвращается nil. При отмене ctx возвращается наименьшая найденная
+// карта и ctx.Err().
+func Reduce(ctx context.Context, g *Graph, fails func(*Graph) bool) (*Graph, error) {
+	r := &reducer{ctx: ctx, fails: fails}
+	if !r.check(g) {
+		return nil, ctx.Err()
+	}
+	best := g.Clone()
+	for {
+		size := reduceSize(best)
+		best = r.rooms(best)
+		best = r.links(best)
+		best = r.ants(best)
+		if ctx.Err() != nil {
+			return best, ctx.Err()
+		}
+		if reduceSize(best) == size {
+			return best, nil
+		}
+	}
+}
+
+type reducer struct {
+	ctx   context.Context
+	fails func(*Graph) bool
+}
+
+// check проверяет сбой на копии кандидата; после отмены ctx
+// кандидаты не принимаются.
+func (r *reducer) check(g *Graph) bool {
+	return r.ctx.Err() == nil && r.fails(g.Clone())
+}
+
+// reduceSize — мера карты для проверки, что проход что-то удалил.
+func reduceSize(g *Graph) int {
+	size := len(g.Rooms)
+	for _, links := range g.Links {
+		size += len(links)
+	}
+	for _, c := range g.sources() {
+		size += c.Ants
+	}
+	return size
+}
+
+// rooms удаляет промежуточные комнаты.
+func (r *reducer) rooms(g *Graph) *Graph {
+	var names []string
+	for name := range g.Rooms {
+		if !g.isTerminal(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	without := func(keep []string) *Graph {
+		kept := make(map[string]bool, len(keep))
+		for _, name := range keep {
+			kept[name] = true
+		}
+		return g.keepRooms(func(name string) bool { return kept[name] || g.isTerminal(name) })
+	}
+	keep := ddmin(names, func(keep []string) bool { return r.check(without(keep)) })
+	if len(keep) == len(names) {
+		return g
+	}
+	return without(keep)
+}
+
+// keepRooms возвращает копию g только с комнатами, для которых keep
+// истинно, и туннелями между ними.
+func (g *Graph) keepRooms(keep func(string) bool) *Graph {
+	c := g.Clone()
+	for name := range c.Rooms {
+		if !keep(name) {
+			delete(c.Rooms, name)
+			delete(c.Links, name)
+		}
+	}
+	for from, links := range c.Links {
+		var kept []string
+		for _, to := range links {
+			if keep(to) {
+				kept = append(kept, to)
+			}
+		}
+		c.Links[from] = kept
+	}
+	for pair := range c.Weights {
+		if !keep(pair[0]) || !keep(pair[1]) {
+			delete(c.Weights, pair)
+		}
+	}
+	return c
+}
+
+// links удаляет туннели; туннели между одной парой комнат
+// (в обе стороны) удаляются вместе.
+func (r *reducer) links(g *Graph) *Graph {
+	seen := make(map[[2]string]bool)
+	var pairs [][2]string
+	for from, links := range g.Links {
+		for _, to := range links {
+			pair := [2]string{min(from, to), max(from, to)}
+			if !seen[pair] {
+				seen[pair] = true
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+	without := func(keep [][2]string) *Graph {
+		kept := make(map[[2]string]bool, len(keep))
+		for _, pair := range keep {
+			kept[pair] = true
+		}
+		c := g.Clone()
+		for _, pair := range pairs {
+			if !kept[pair] {
+				c.RemoveLink(pair[0], pair[1])
+			}
+		}
+		return c
+	}
+	keep := ddmin(pairs, func(keep [][2]string) bool { return r.check(without(keep)) })
+	if len(keep) == len(pairs) {
+		return g
+	}
+	return without(keep)
+}
+
+// ants уменьшает число муравьёв (в режиме колоний — каждой колонии):
+// сначала вдвое, пока сбой сохраняется, затем по одному.
+func (r *reducer) ants(g *Graph) *Graph {
+	for i := range g.sources() {
+		withAnts := func(n int) *Graph {
+			c := g.Clone()
+			if len(c.Colonies) == 0 {
+				c.NumAnts = n
+				return c
+			}
+			c.NumAnts += n - c.Colonies[i].Ants
+			c.Colonies[i].Ants = n
+			return c
+		}
+		n := g.sources()[i].Ants
+		for n > 1 && r.check(withAnts(n/2)) {
+			n /= 2
+		}
+		for n > 1 && r.check(withAnts(n-1)) {
+			n--
+		}
+		if n != g.sources()[i].Ants {
+			g = withAnts(n)
+		}
+	}
+	return g
+}
+
+// ddmin возвращает подмножество items, на котором test ещё истинен
+// и из которого нельзя убрать ни одного элемента (1-минимальное):
+// items делится на n частей, и каждая часть по очереди отбрасывается;
+// если ни одну отбросить нельзя, части мельчают вдвое.
+func ddmin[T any](items []T, test func([]T) bool) []T {
+	n := min(2, len(items))
+	for len(items) > 0 {
+		chunk := (len(items) + n - 1) / n
+		reduced := false
+		for start := 0; start < len(items); start += chunk {
+			rest := append(append([]T(nil), items[:start]...), items[min(start+chunk, len(items)):]...)
+			if test(rest) {
+				items, reduced = rest, true
+				n = max(n-1, min(2, len(items)))
+				break
+			}
+		}
+		if reduced {
+			continue
+		}
+		if n >= len(items) {
+			break
+		}
+		n = min(n*2, len(items))
+	}
+	return items
+}
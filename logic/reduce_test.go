@@ -0,0 +1,146 @@
+package logic
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDDMin(t *testing.T) {
+	items := make([]int, 20)
+	for i := range items {
+		items[i] = i
+	}
+	for _, tc := range []struct {
+		name string
+		test func([]int) bool
+		want []int
+	}{
+		{"all of 3, 7, 15", func(s []int) bool {
+			return slices.Contains(s, 3) && slices.Contains(s, 7) && slices.Contains(s, 15)
+		}, []int{3, 7, 15}},
+		{"sum at least 30", func(s []int) bool {
+			sum := 0
+			for _, v := range s {
+				sum += v
+			}
+			return sum >= 30
+		}, nil},
+		{"always", func([]int) bool { return true }, []int{}},
+	} {
+		got := ddmin(items, tc.test)
+		if !tc.test(got) {
+			t.Errorf("%s: %v does not satisfy the predicate", tc.name, got)
+			continue
+		}
+		if tc.want != nil && !slices.Equal(got, tc.want) {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+		// 1-минимальность: ни один элемент нельзя убрать
+		for i := range got {
+			if rest := slices.Delete(slices.Clone(got), i, i+1); tc.test(rest) {
+				t.Errorf("%s: %v is not 1-minimal, %v still satisfies the predicate", tc.name, got, rest)
+			}
+		}
+	}
+}
+
+// paddedMap — сбой "муравьи идут через x" воспроизводится на s-x-e
+// с одним муравьём; остальные комнаты и туннели лишние.
+const paddedMap = `5
+##start
+s 0 0
+##end
+e 4 0
+x 2 0
+p1 1 1
+p2 2 1
+p3 3 1
+p4 1 2
+p5 2 2
+p6 3 2
+s-x
+x-e
+s-p1
+p1-p2
+p2-p3
+p3-e
+s-p4
+p4-p5
+p5-p6
+p6-e
+x-p2
+p5-x
+`
+
+// usesRoom — предикат сбоя: решение ведёт муравьёв через room.
+func usesRoom(room string) func(*Graph) bool {
+	return func(g *Graph) bool {
+		resp := Solve(context.Background(), g, Options{})
+		if resp.Error != "" {
+			return false
+		}
+		for _, use := range resp.Paths {
+			if slices.Contains(use.Path, room) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func TestReduce(t *testing.T) {
+	g, err := Parse(paddedMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Reduce(context.Background(), g, usesRoom("x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := Parse("1\n##start\ns 0 0\n##end\ne 4 0\nx 2 0\ns-x\nx-e\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Equivalent(got, want) {
+		t.Errorf("reduced to\n%s\nwant s-x-e with one ant", strings.Join(got.Lines(), "\n"))
+	}
+	if len(g.Rooms) != 9 || g.NumAnts != 5 {
+		t.Errorf("Reduce changed the input graph: %d rooms, %d ants", len(g.Rooms), g.NumAnts)
+	}
+}
+
+func TestReduceColonies(t *testing.T) {
+	g, err := Parse(twoColonies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Reduce(context.Background(), g, func(c *Graph) bool {
+		return len(c.Colonies) == 2 && Solve(context.Background(), c, Options{}).Error == ""
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := 0
+	for _, c := range got.Colonies {
+		sum += c.Ants
+	}
+	if got.NumAnts != sum {
+		t.Errorf("NumAnts %d, colony sizes sum to %d", got.NumAnts, sum)
+	}
+	if _, err := Parse(strings.Join(got.Lines(), "\n")); err != nil {
+		t.Errorf("reduced map does not parse: %v", err)
+	}
+}
+
+func TestReduceNotFailing(t *testing.T) {
+	g, err := Parse(paddedMap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Reduce(context.Background(), g, usesRoom("missing"))
+	if got != nil || err != nil {
+		t.Errorf("got %v, %v; want nil when the map does not fail", got, err)
+	}
+}
@@ -0,0 +1,62 @@
+package logic
+
+import (
+	"fmt"
+)
+
+// Rules задаёт правила движения муравьёв. Значения упорядочены
+// по строгости: каждое следующее включает ограничения предыдущего.
+type Rules int
+
+const (
+	// RulesVertexDisjoint — комната вмещает не больше Capacity муравьёв,
+	// туннели не ограничены (поведение по умолчанию).
+	RulesVertexDisjoint Rules = iota
+	// RulesSwapForbidden — дополнительно два муравья не могут пройти
+	// один туннель навстречу друг другу за один ход.
+	RulesSwapForbidden
+	// RulesEdgeCapacity — каждый туннель за ход пропускает не больше
+	// одного муравья в любую сторону.
+	RulesEdgeCapacity
+)
+
+// ParseRules разбирает название правил: "vertex", "no-swap" или "edge".
+func ParseRules(name string) (Rules, error) {
+	switch name {
+	case "", "vertex":
+		return RulesVertexDisjoint, nil
+	case "no-swap":
+		return RulesSwapForbidden, nil
+	case "edge":
+		return RulesEdgeCapacity, nil
+	}
+	return 0, fmt.Errorf("unknown rules %q (want vertex, no-swap or edge)", name)
+}
+
+func (r Rules) String() string {
+	switch r {
+	case RulesSwapForbidden:
+		return "no-swap"
+	case RulesEdgeCapacity:
+		return "edge"
+	}
+	return "vertex"
+}
+
+// allows сообщает, можно ли пройти туннель from→to, если за текущий
+// ход уже пройдены туннели crossed (ключ — {откуда, куда}).
+func (r Rules) allows(crossed map[[2]string]bool, from, to string) bool {
+	switch r {
+	case RulesSwapForbidden:
+		return !crossed[[2]string{to, from}]
+	case RulesEdgeCapacity:
+		return !crossed[[2]string{from, to}] && !crossed[[2]string{to, from}]
+	}
+	return true
+}
+
+// limitsTunnels сообщает, что два пути, проходящие через один туннель,
+// несовместимы: при движении в ногу они заняли бы его в один ход.
+func (r Rules) limitsTunnels() bool {
+	return r != RulesVertexDisjoint
+}
@@ -0,0 +1,191 @@
+package logic
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Schedule — план движения всех муравьёв, составленный до симуляции:
+// какой муравей по какому пути идёт, когда выходит со старта и когда
+// приходит в финиш. Симуляция следует плану в точности, если его
+// принимает CheckSchedule.
+type Schedule struct {
+	Turns int       `json:"turns"`
+	Paths []Path    `json:"paths"`
+	Ants  []AntPlan `json:"ants"`
+}
+
+// AntPlan — план одного муравья.
+type AntPlan struct {
+	Ant     string  `json:"ant"`     // обозначение в выводе: L3 или L2.3
+	Colony  int     `json:"colony"`  // индекс колонии (0 в классическом режиме)
+	Path    int     `json:"path"`    // индекс в Schedule.Paths
+	Launch  int     `json:"launch"`  // ход выхода со старта
+	Arrival int     `json:"arrival"` // ход прибытия в финиш
+	Visits  []Visit `json:"visits"`  // комнаты после каждого хода муравья
+}
+
+// Visit — муравей входит в комнату Room на ходу Turn.
+type Visit struct {
+	Room string `json:"room"`
+	Turn int    `json:"turn"`
+}
+
+// CheckSchedule возвращает ошибку, если план для карты g при опциях
+// opts может разойтись с ходами симуляции. План быстрого решателя
+// считает, что муравьи идут без остановок, а ждать их заставляют
+// события, правила no-swap и edge и общие комнаты вместимостью больше
+// одного муравья. План точного решателя строится по его ходам
+// и совпадает с ними всегда.
+func CheckSchedule(g *Graph, opts Options) error {
+	if opts.Exact {
+		return nil
+	}
+	if len(opts.Events) > 0 {
+		return errors.New("the schedule cannot account for events")
+	}
+	if opts.Rules != RulesVertexDisjoint {
+		return fmt.Errorf("the schedule assumes vertex rules, not %s", opts.Rules)
+	}
+	for name := range g.Rooms {
+		if !g.isTerminal(name) && g.Capacity(name) > 1 {
+			return fmt.Errorf("the schedule cannot account for room %s of capacity %d", name, g.Capacity(name))
+		}
+	}
+	return nil
+}
+
+// buildSchedule повторяет порядок выпуска муравьёв симуляцией: каждый ход
+// группы и пути перебираются по порядку, на путь выходит не больше его
+// пропускной способности. Пути не пересекаются, поэтому муравей идёт
+// без остановок и приходит на ходу Launch+len(path)-2.
+func buildSchedule(g *Graph, fleets []fleet) *Schedule {
+	sched := &Schedule{}
+	offsets := make([]int, len(fleets))
+	remaining := make([][]int, len(fleets))
+	pending := 0
+	for f, fl := range fleets {
+		offsets[f] = len(sched.Paths)
+		sched.Paths = append(sched.Paths, fl.paths...)
+		remaining[f] = append([]int{}, fl.counts...)
+		for _, c := range fl.counts {
+			pending += c
+		}
+	}
+
+	for turn := 1; pending > 0; turn++ {
+		for f, fl := range fleets {
+			_, rates := pathRates(g, fl.paths, fl.ants)
+			for i, path := range fl.paths {
+				for sent := 0; sent < rates[i] && remaining[f][i] > 0; sent++ {
+					remaining[f][i]--
+					pending--
+					plan := AntPlan{
+						Colony:  f,
+						Path:    offsets[f] + i,
+						Launch:  turn,
+						Arrival: turn + len(path) - 2,
+					}
+					for k := 1; k < len(path); k++ {
+						plan.Visits = append(plan.Visits, Visit{Room: path[k], Turn: turn + k - 1})
+					}
+					sched.Ants = append(sched.Ants, plan)
+					sched.Turns = max(sched.Turns, plan.Arrival)
+				}
+			}
+		}
+	}
+	labelPlans(g, sched)
+	return sched
+}
+
+// scheduleFromTracks строит план по расписаниям точного решателя:
+// муравьи могут ждать в пути, поэтому комнаты перечислены с ходами входа.
+func scheduleFromTracks(g *Graph, tracks []antTrack) *Schedule {
+	sched := &Schedule{}
+	pathIndex := make(map[string]int)
+	for _, track := range tracks {
+		plan := AntPlan{Colony: track.colony}
+		route := Path{track.rooms[0]}
+		for t := 1; t < len(track.rooms); t++ {
+			if track.rooms[t] == track.rooms[t-1] {
+				continue
+			}
+			if plan.Launch == 0 {
+				plan.Launch = t
+			}
+			plan.Arrival = t
+			route = append(route, track.rooms[t])
+			plan.Visits = append(plan.Visits, Visit{Room: track.rooms[t], Turn: t})
+		}
+		key := strings.Join(route, " ")
+		idx, ok := pathIndex[key]
+		if !ok {
+			idx = len(sched.Paths)
+			pathIndex[key] = idx
+			sched.Paths = append(sched.Paths, route)
+		}
+		plan.Path = idx
+		sched.Ants = append(sched.Ants, plan)
+		sched.Turns = max(sched.Turns, plan.Arrival)
+	}
+	labelPlans(g, sched)
+	return sched
+}
+
+// pathUses считает муравьёв на каждом пути плана.
+func (s *Schedule) pathUses() []PathUse {
+	uses := make([]PathUse, len(s.Paths))
+	for i, path := range s.Paths {
+		uses[i].Path = path
+	}
+	for _, plan := range s.Ants {
+		uses[plan.Path].Ants++
+	}
+	return uses
+}
+
+// labelPlans нумерует муравьёв внутри колоний в порядке плана.
+func labelPlans(g *Graph, sched *Schedule) {
+	seen := make(map[int]int)
+	for i := range sched.Ants {
+		plan := &sched.Ants[i]
+		seen[plan.Colony]++
+		plan.Ant = antLabel(g, plan.Colony, seen[plan.Colony])
+	}
+}
+
+// WriteJSON записывает план в формате JSON.
+func (s *Schedule) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(s)
+}
+
+// WriteCSV записывает план в формате CSV: строка на муравья, комнаты —
+// через ';' в виде room@turn.
+func (s *Schedule) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"ant", "colony", "path", "launch", "arrival", "visits"})
+	for _, plan := range s.Ants {
+		visits := make([]string, len(plan.Visits))
+		for i, v := range plan.Visits {
+			visits[i] = v.Room + "@" + strconv.Itoa(v.Turn)
+		}
+		cw.Write([]string{
+			plan.Ant,
+			strconv.Itoa(plan.Colony),
+			strconv.Itoa(plan.Path),
+			strconv.Itoa(plan.Launch),
+			strconv.Itoa(plan.Arrival),
+			strings.Join(visits, ";"),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,72 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestScheduleMatchesMoves проверяет, что план, который принимает
+// CheckSchedule, в точности повторяет ходы симуляции.
+func TestScheduleMatchesMoves(t *testing.T) {
+	forEachExample(t, func(t *testing.T, _ string, g *Graph) {
+		for _, exact := range []bool{false, true} {
+			t.Run(fmt.Sprintf("exact=%v", exact), func(t *testing.T) {
+				opts := Options{Exact: exact, Schedule: true}
+				if err := CheckSchedule(g, opts); err != nil {
+					t.Skip(err)
+				}
+				resp := Solve(context.Background(), g, opts)
+				if resp.Error != "" {
+					t.Fatal(resp.Error)
+				}
+				if got := scheduleMoves(resp.Schedule); !slices.Equal(got, resp.Output) {
+					t.Errorf("schedule gives %d turns, simulation %d; first turns %q vs %q",
+						len(got), len(resp.Output), got[:min(3, len(got))], resp.Output[:min(3, len(resp.Output))])
+				}
+			})
+		}
+	})
+}
+
+// scheduleMoves восстанавливает строки ходов по плану.
+func scheduleMoves(sched *Schedule) []string {
+	turns := make([][]string, sched.Turns)
+	for _, plan := range sched.Ants {
+		for _, v := range plan.Visits {
+			turns[v.Turn-1] = append(turns[v.Turn-1], plan.Ant+"-"+v.Room)
+		}
+	}
+	moves := make([]string, len(turns))
+	for i, turn := range turns {
+		moves[i] = strings.Join(turn, " ")
+	}
+	return moves
+}
+
+// TestScheduleRefused проверяет, что Solve не отдаёт план, который
+// отклоняет CheckSchedule, но пути решения возвращает всегда.
+func TestScheduleRefused(t *testing.T) {
+	g, err := Parse(twoColonies)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, opts := range []Options{
+		{Rules: RulesEdgeCapacity, Schedule: true},
+		{Rules: RulesSwapForbidden, Schedule: true},
+		{Events: []Event{{Turn: 1, Link: [2]string{"a", "e"}}}, Schedule: true},
+	} {
+		resp := Solve(context.Background(), g, opts)
+		if resp.Error != "" {
+			t.Fatal(resp.Error)
+		}
+		if resp.Schedule != nil || len(resp.Paths) == 0 {
+			t.Errorf("rules %s, %d events: schedule %v, %d paths; want no schedule and the paths", opts.Rules, len(opts.Events), resp.Schedule != nil, len(resp.Paths))
+		}
+	}
+	if resp := Solve(context.Background(), g, Options{Schedule: true}); resp.Schedule == nil {
+		t.Error("no schedule under vertex rules")
+	}
+}
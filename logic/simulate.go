@@ -1,7 +1,10 @@
 package logic
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"iter"
 	"sort"
 	"strings"
 )
@@ -9,85 +12,434 @@ import (
 // RunSimulation — входная точка движка. Парсит вход, выбирает пути
 // и выполняет пошаговую симуляцию перемещения муравьёв.
 func RunSimulation(input string) Response {
-	lines := strings.Split(strings.TrimSpace(input), "\n")
-	g, err := parseLines(lines)
+	return RunSimulationWithOptions(input, Options{})
+}
+
+// RunSimulationWithOptions работает как RunSimulation, но позволяет
+// выбрать режим решателя (см. Options).
+func RunSimulationWithOptions(input string, opts Options) Response {
+	return RunSimulationContext(context.Background(), input, opts)
+}
+
+// RunSimulationContext работает как RunSimulationWithOptions, но прерывает
+// поиск путей и симуляцию, когда ctx отменён или истёк его срок.
+func RunSimulationContext(ctx context.Context, input string, opts Options) Response {
+	g, err := Parse(input)
 	if err != nil {
 		return Response{Error: "ERROR: invalid data format"}
 	}
-	paths := choosePathsHybrid(g, g.NumAnts)
-	if len(paths) == 0 {
-		return Response{Error: "ERROR: no valid paths found"}
+	return Solve(ctx, g, opts)
+}
+
+// Parse разбирает текст карты. В отличие от RunSimulation, ошибка
+// сообщает причину и, где это возможно, номер строки.
+func Parse(input string) (*Graph, error) {
+	return parseLines(strings.Split(strings.TrimSpace(input), "\n"))
+}
+
+// Solve решает уже разобранную карту: выбирает пути, распределяет
+// муравьёв и выполняет симуляцию. Граф g не изменяется.
+func Solve(ctx context.Context, g *Graph, opts Options) Response {
+	var tr *trace
+	if opts.Explain {
+		tr = &trace{}
+		ctx = withTrace(ctx, tr)
+	}
+	resp := solve(ctx, g, opts)
+	if tr != nil {
+		resp.Explain = tr.lines
 	}
-	isSuurballe := float64(len(g.Links))/float64(len(g.Rooms)) > 2.0 || g.NumAnts > 20
-	moves := moveAnts(paths, g.NumAnts, isSuurballe)
-	return Response{Output: moves}
+	return resp
 }
 
-// moveAnts выполняет пошаговую симуляцию и возвращает срез строк ходов
-// в формате L<id>-<room> для каждого шага, объединённых пробелами.
-func moveAnts(paths []Path, ants int, isSuurballe bool) []string {
+func solve(ctx context.Context, g *Graph, opts Options) Response {
+	sol, err := plan(ctx, g, opts)
+	if err != nil {
+		return Response{Error: "ERROR: " + err.Error()}
+	}
+	return sol.run(ctx, g, opts)
+}
+
+// Plan — пути и распределение муравьёв (или расписания точного
+// решателя), выбранные так же, как в Solve, но ещё без симуляции.
+// Позволяет замерять выбор путей и симуляцию по отдельности.
+type Plan struct {
+	g    *Graph
+	opts Options
+	sol  *solution
+}
+
+// NewPlan выбирает пути для карты g при опциях opts, не выполняя
+// симуляцию. Граф g не изменяется.
+func NewPlan(ctx context.Context, g *Graph, opts Options) (*Plan, error) {
+	sol, err := plan(ctx, g, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &Plan{g: g, opts: opts, sol: sol}, nil
+}
+
+// Run выполняет симуляцию по плану и возвращает тот же ответ, что Solve
+// (без Explain). План не расходуется, Run можно вызывать повторно.
+func (p *Plan) Run(ctx context.Context) Response {
+	// Симуляция расходует counts, а события меняют пути и граф
+	sol := &solution{g: p.sol.g, tracks: p.sol.tracks, fleets: make([]fleet, len(p.sol.fleets))}
+	for i, f := range p.sol.fleets {
+		f.paths = append([]Path(nil), f.paths...)
+		f.counts = append([]int(nil), f.counts...)
+		sol.fleets[i] = f
+	}
+	if len(p.opts.Events) > 0 {
+		sol.g = sol.g.Clone()
+	}
+	return sol.run(ctx, p.g, p.opts)
+}
+
+// run выполняет симуляцию по плану или переводит в ходы расписания
+// точного решателя; g — исходная карта (для CheckSchedule).
+func (sol *solution) run(ctx context.Context, g *Graph, opts Options) Response {
+	if sol.tracks != nil {
+		schedule := scheduleFromTracks(sol.g, sol.tracks)
+		resp := Response{Output: tracksToMoves(sol.g, sol.tracks), Paths: schedule.pathUses()}
+		if opts.Schedule {
+			resp.Schedule = schedule
+		}
+		return resp
+	}
+	// Распределение снимается до симуляции: она расходует counts
+	var uses []PathUse
+	for _, f := range sol.fleets {
+		for i, path := range f.paths {
+			if f.counts[i] > 0 {
+				uses = append(uses, PathUse{Path: path, Ants: f.counts[i]})
+			}
+		}
+	}
+	var schedule *Schedule
+	if opts.Schedule && CheckSchedule(g, opts) == nil {
+		schedule = buildSchedule(sol.g, sol.fleets)
+	}
+	moves, reroutes, err := moveAnts(ctx, sol.g, sol.fleets, opts.Events)
+	if err != nil {
+		// Неполное решение никогда не выдаётся за успешное
+		return Response{Error: "ERROR: " + err.Error()}
+	}
+	return Response{Output: moves, Paths: uses, Reroutes: reroutes, Schedule: schedule}
+}
+
+// solution — результат планирования: группы муравьёв с путями
+// для симуляции или готовые расписания точного решателя.
+type solution struct {
+	g      *Graph // копия карты с развёрнутыми взвешенными туннелями
+	fleets []fleet
+	tracks []antTrack // только при Options.Exact
+}
+
+// plan готовит копию карты и выбирает пути (или расписания точного
+// решателя) согласно opts. Симуляция не выполняется.
+func plan(ctx context.Context, g *Graph, opts Options) (*solution, error) {
+	g = g.Clone()
+	// Взвешенные туннели превращаются в цепочки комнат; ходы внутри
+	// туннеля выводятся как L<id>-<a>-<b>:<k>.
+	g.Rules = opts.Rules
+	opts.Order.sortLinks(g)
+	g, err := expandWeightedLinks(ctx, g)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkEvents(g, opts.Events); err != nil {
+		return nil, fmt.Errorf("invalid events: %v", err)
+	}
+	if opts.Exact {
+		if len(opts.Events) > 0 {
+			return nil, errors.New("events are not supported by the exact solver")
+		}
+		tracks, err := solveExact(ctx, g)
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if errors.Is(err, ErrExactTooLarge) {
+			return nil, err
+		}
+		if err != nil {
+			return nil, ErrNoPaths
+		}
+		if tr := traceFrom(ctx); tr != nil && len(tracks) > 0 {
+			tr.add("strategy: exact solver (--exact): min-cost flow in the time-expanded network, minimal horizon %d turns", len(tracks[0].rooms)-1)
+			tr.add("  ants wait or move per turn as the flow decides; no path selection or distribution step")
+		}
+		return &solution{g: g, tracks: tracks}, nil
+	}
+
+	tr := traceFrom(ctx)
+	if len(g.Colonies) > 0 {
+		fleets, err := planColonies(g, opts.Order)
+		if err != nil {
+			return nil, ErrNoPaths
+		}
+		tr.add("strategy: multi-colony successive shortest paths — the slowest colony gets the next path while its turn count drops")
+		for i, f := range fleets {
+			_, turns := distributePaths(g, f.paths, f.ants)
+			tr.add("colony %d (start %s):", i+1, g.Colonies[i].Start)
+			tr.distribution(g, f.paths, f.counts, f.ants, turns)
+		}
+		return &solution{g: g, fleets: fleets}, nil
+	}
+	paths := choosePathsHybrid(ctx, g, g.NumAnts)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
 	if len(paths) == 0 {
-		return nil
+		return nil, ErrNoPaths
 	}
+	opts.Order.sortPaths(paths)
+	counts, turns := distributePaths(g, paths, g.NumAnts)
+	tr.distribution(g, paths, counts, g.NumAnts, turns)
+	return &solution{g: g, fleets: []fleet{{paths: paths, counts: counts, ants: g.NumAnts}}}, nil
+}
+
+// ErrNoPaths — ни один финиш не достижим со старта; в Response.Error
+// выводится как "ERROR: no valid paths found".
+var ErrNoPaths = errors.New("no valid paths found")
 
-	var counts []int
-	if isSuurballe {
-		_, counts = calcTimeAndDistribute(paths, ants)
-	} else {
-		turns := calcTime(paths, ants)
-		counts = distributeAnts(paths, ants, turns)
+// directTunnelCapacity — сколько муравьёв за ход проходит по туннелю,
+// напрямую соединяющему старт и финиш: при двух и менее муравьях
+// все уходят сразу, иначе (и всегда при RulesEdgeCapacity) по одному за ход.
+func directTunnelCapacity(rules Rules, ants int) int {
+	if ants <= 2 && rules != RulesEdgeCapacity {
+		return ants
 	}
+	return 1
+}
 
-	type antOnPath struct {
-		id      int
-		pathIdx int
-		pos     int
+// moveAnts выполняет пошаговую симуляцию и возвращает срез строк ходов
+// в формате L<id>-<room> для каждого шага, объединённых пробелами
+// (ход, в котором все ждут события, — пустая строка), и точки
+// перестроения путей после событий. Группы муравьёв (fleets)
+// делят общий граф: занятость комнат общая, поэтому муравьи разных
+// колоний не сталкиваются. Если симуляция застревает, возвращается
+// *DeadlockError.
+func moveAnts(ctx context.Context, g *Graph, fleets []fleet, events []Event) ([]string, []Reroute, error) {
+	return newSimulator(g, fleets, events).run(ctx)
+}
+
+// antLabel возвращает обозначение муравья в выводе: L<id>, а в режиме
+// колоний — L<колония>.<id>, где номер колонии считается с единицы.
+func antLabel(g *Graph, colony, id int) string {
+	if len(g.Colonies) == 0 {
+		return fmt.Sprintf("L%d", id)
 	}
+	return fmt.Sprintf("L%d.%d", colony+1, id)
+}
 
-	var active []antOnPath
+// fleet — группа муравьёв с общими путями: в классическом режиме одна
+// на весь муравейник, в режиме колоний — по одной на каждую колонию
+// (в порядке g.Colonies).
+type fleet struct {
+	paths  []Path
+	counts []int // сколько муравьёв отправить по каждому пути
+	ants   int
+}
+
+// antOnPath — муравей в пути: собственная копия пути нужна, чтобы
+// перестроить маршрут одного муравья после события.
+type antOnPath struct {
+	id   int
+	path Path
+	pos  int
+}
+
+// move — один ход муравья для вывода.
+type move struct {
+	fleet, id int
+	from      string
+	room      string
+}
+
+// simulator хранит состояние пошаговой симуляции между ходами.
+type simulator struct {
+	g        *Graph
+	fleets   []fleet
+	events   []Event
+	active   [][]antOnPath
+	nextID   []int
+	finished int
+	total    int
+	turn     int
+	// Число муравьёв в каждой промежуточной комнате сохраняется между
+	// ходами и сравнивается с вместимостью комнаты.
+	occupancy map[string]int
+	arrived   map[string]int // сколько муравьёв дошло до каждого финиша
+	reroutes  []Reroute
+}
+
+func newSimulator(g *Graph, fleets []fleet, events []Event) *simulator {
+	s := &simulator{
+		g:         g,
+		fleets:    fleets,
+		events:    events,
+		active:    make([][]antOnPath, len(fleets)),
+		nextID:    make([]int, len(fleets)),
+		occupancy: make(map[string]int),
+		arrived:   make(map[string]int),
+	}
+	for f := range fleets {
+		s.nextID[f] = 1
+		s.total += fleets[f].ants
+	}
+	return s
+}
+
+func (s *simulator) hasRoom(room string) bool {
+	return s.occupancy[room] < s.g.Capacity(room)
+}
+
+// open сообщает, что туннель from→to не закрыт событием.
+func (s *simulator) open(from, to string) bool {
+	return len(s.events) == 0 || hasLink(s.g, from, to)
+}
+
+// run выполняет ходы до прибытия всех муравьёв и возвращает строки ходов
+// и точки перестроения путей. Ход без единого перемещения означает,
+// что состояние больше не изменится (если впереди нет событий), —
+// тогда возвращается *DeadlockError вместо неполного решения.
+// При отмене ctx возвращается ctx.Err().
+func (s *simulator) run(ctx context.Context) ([]string, []Reroute, error) {
 	moves := []string{}
-	nextID := 1
-	finished := 0
+	for step, err := range s.turns(ctx) {
+		if err != nil {
+			return nil, s.reroutes, err
+		}
+		moves = append(moves, s.format(step))
+	}
+	return moves, s.reroutes, nil
+}
+
+// turns — итератор по ходам симуляции: отдаёт перемещения каждого
+// хода сразу после его вычисления, так что n-й элемент — ход n.
+// Ход, в котором все ждут события, отдаётся пустым. Ошибка (тупик
+// или отмена ctx) отдаётся последним элементом. Прерванная
+// потребителем итерация оставляет симулятор в текущем ходу.
+func (s *simulator) turns(ctx context.Context) iter.Seq2[[]move, error] {
+	return func(yield func([]move, error) bool) {
+		for s.finished < s.total {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+			step := s.step()
+			if len(step) == 0 && !s.eventsPending() {
+				yield(nil, s.deadlock())
+				return
+			}
+			if !yield(step, nil) {
+				return
+			}
+		}
+	}
+}
 
-	totalAntsToLaunch := 0
-	for _, count := range counts {
-		totalAntsToLaunch += count
+// eventsPending сообщает, что впереди есть события, способные
+// изменить пути застрявших муравьёв.
+func (s *simulator) eventsPending() bool {
+	for _, ev := range s.events {
+		if ev.Turn > s.turn {
+			return true
+		}
 	}
+	return false
+}
 
-	if totalAntsToLaunch > ants {
-		scale := float64(ants) / float64(totalAntsToLaunch)
-		remainder := ants
-		for i := range counts {
-			counts[i] = int(float64(counts[i]) * scale)
-			remainder -= counts[i]
+// deadlock собирает состояние застрявшей симуляции.
+func (s *simulator) deadlock() *DeadlockError {
+	err := &DeadlockError{Turn: s.turn}
+	for f, fl := range s.fleets {
+		for _, a := range s.active[f] {
+			err.Stuck = append(err.Stuck, AntPosition{Ant: antLabel(s.g, f, a.id), Room: a.path[a.pos]})
 		}
-		for i := 0; i < remainder && i < len(counts); i++ {
-			counts[i]++
+		for i, path := range fl.paths {
+			if fl.counts[i] > 0 {
+				err.Remaining = append(err.Remaining, PathRemaining{Path: path, Ants: fl.counts[i]})
+			}
 		}
 	}
+	return err
+}
 
-	for finished < ants {
-		roomOcc := make(map[string]bool)
-		var step []string
-		var nextActive []antOnPath
+// DeadlockError — симуляция застряла: за ход ни один муравей
+// не сдвинулся и ни один не вышел со старта, хотя дошли не все.
+type DeadlockError struct {
+	Turn      int             // ход, на котором движение остановилось
+	Stuck     []AntPosition   // муравьи в пути и их комнаты
+	Remaining []PathRemaining // ещё не вышедшие муравьи по путям
+}
+
+// AntPosition — муравей и комната, в которой он находится.
+type AntPosition struct {
+	Ant  string
+	Room string
+}
+
+// PathRemaining — путь и число муравьёв, которые ещё должны по нему выйти.
+type PathRemaining struct {
+	Path Path
+	Ants int
+}
+
+func (e *DeadlockError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "simulation deadlocked at turn %d", e.Turn)
+	if len(e.Stuck) > 0 {
+		b.WriteString("; stuck:")
+		for _, a := range e.Stuck {
+			fmt.Fprintf(&b, " %s in %s", a.Ant, a.Room)
+		}
+	}
+	if len(e.Remaining) > 0 {
+		b.WriteString("; waiting:")
+		for _, r := range e.Remaining {
+			fmt.Fprintf(&b, " %d on %s", r.Ants, strings.Join(r.Path, "-"))
+		}
+	}
+	return b.String()
+}
+
+// step выполняет один ход: применяет события этого хода, двигает
+// активных муравьёв и запускает новых.
+func (s *simulator) step() []move {
+	s.turn++
+	for _, ev := range s.events {
+		if ev.Turn == s.turn {
+			s.apply(ev)
+		}
+	}
 
-		// Движение активных муравьев
-		for _, a := range active {
-			path := paths[a.pathIdx]
+	var step []move
+	// Туннели, пройденные за этот ход, — для ограничений g.Rules
+	crossed := make(map[[2]string]bool)
+
+	// Движение активных муравьев
+	for f := range s.fleets {
+		var nextActive []antOnPath
+		for _, a := range s.active[f] {
+			path := a.path
 			nextPos := a.pos + 1
 			if nextPos < len(path) {
 				room := path[nextPos]
-				if nextPos == len(path)-1 || !roomOcc[room] {
-					if a.pos > 0 {
-						roomOcc[path[a.pos]] = false
+				from := path[a.pos]
+				if (nextPos == len(path)-1 || s.hasRoom(room)) && s.open(from, room) && s.g.Rules.allows(crossed, from, room) {
+					crossed[[2]string{from, room}] = true
+					if !s.g.isTerminal(from) {
+						s.occupancy[from]--
 					}
-					if nextPos != len(path)-1 {
-						roomOcc[room] = true
+					if !s.g.isTerminal(room) {
+						s.occupancy[room]++
 					}
 					a.pos = nextPos
-					step = append(step, fmt.Sprintf("L%d-%s", a.id, room))
+					step = append(step, move{f, a.id, from, room})
 					if nextPos == len(path)-1 {
-						finished++
+						s.finished++
+						s.arrived[room]++
 					} else {
 						nextActive = append(nextActive, a)
 					}
@@ -96,68 +448,54 @@ func moveAnts(paths []Path, ants int, isSuurballe bool) []string {
 				}
 			}
 		}
-		active = nextActive
+		s.active[f] = nextActive
+	}
 
-		// Запуск новых муравьев
-		for i, path := range paths {
-			if counts[i] == 0 || nextID > ants {
+	// Запуск новых муравьев
+	for f, fl := range s.fleets {
+		counts := fl.counts
+		for i, path := range fl.paths {
+			if counts[i] == 0 || s.nextID[f] > fl.ants {
 				continue
 			}
 			if len(path) < 2 {
 				continue
 			}
 			room := path[1]
-			// Для прямого пути: если муравьев <= 2, отправляем всех сразу, иначе по одному
+			// Прямой путь старт→финиш пропускает ограниченное число муравьёв за ход
 			if len(path) == 2 {
-				if ants <= 2 {
-					// Отправляем всех муравьев в один шаг
-					for counts[i] > 0 && nextID <= ants {
-						step = append(step, fmt.Sprintf("L%d-%s", nextID, room))
-						finished++
-						counts[i]--
-						nextID++
-					}
-				} else if !roomOcc[room] {
-					// Отправляем одного муравья за шаг
-					step = append(step, fmt.Sprintf("L%d-%s", nextID, room))
-					roomOcc[room] = true
-					finished++
+				for sent := 0; sent < directTunnelCapacity(s.g.Rules, fl.ants) && counts[i] > 0 && s.nextID[f] <= fl.ants; sent++ {
+					step = append(step, move{f, s.nextID[f], path[0], room})
+					s.finished++
+					s.arrived[room]++
 					counts[i]--
-					nextID++
-				}
-			} else if !roomOcc[room] {
-				step = append(step, fmt.Sprintf("L%d-%s", nextID, room))
-				if len(path) > 2 {
-					roomOcc[room] = true
-					active = append(active, antOnPath{id: nextID, pathIdx: i, pos: 1})
-				} else {
-					finished++
+					s.nextID[f]++
 				}
+			} else if s.hasRoom(room) && s.open(path[0], room) && s.g.Rules.allows(crossed, path[0], room) {
+				crossed[[2]string{path[0], room}] = true
+				step = append(step, move{f, s.nextID[f], path[0], room})
+				s.occupancy[room]++
+				s.active[f] = append(s.active[f], antOnPath{id: s.nextID[f], path: path, pos: 1})
 				counts[i]--
-				nextID++
+				s.nextID[f]++
 			}
 		}
+	}
 
-		if len(step) > 0 {
-			sort.Slice(step, func(i, j int) bool {
-				var ni, nj int
-				fmt.Sscanf(step[i], "L%d-", &ni)
-				fmt.Sscanf(step[j], "L%d-", &nj)
-				return ni < nj
-			})
-			moves = append(moves, strings.Join(step, " "))
-		}
-
-		// Если все муравьи достигли конца, выходим
-		if finished == ants {
-			break
+	sort.Slice(step, func(i, j int) bool {
+		if step[i].fleet != step[j].fleet {
+			return step[i].fleet < step[j].fleet
 		}
+		return step[i].id < step[j].id
+	})
+	return step
+}
 
-		// Защита от бесконечного цикла
-		if len(moves) > ants*100 {
-			break
-		}
+// format собирает ходы одного шага в строку "L<id>-<room> ...".
+func (s *simulator) format(step []move) string {
+	parts := make([]string, len(step))
+	for i, m := range step {
+		parts[i] = fmt.Sprintf("%s-%s", antLabel(s.g, m.fleet, m.id), m.room)
 	}
-
-	return moves
+	return strings.Join(parts, " ")
 }
@@ -0,0 +1,497 @@
+package logic
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Точный решатель: сеть, развёрнутая во времени (комната × ход).
+// Каждая промежуточная комната в каждый ход вмещает Capacity муравьёв;
+// при RulesEdgeCapacity туннель за ход пропускает одного муравья. Ищется минимальное T,
+// при котором из старта в финиш проходит NumAnts единиц потока, затем
+// на этом T строится поток минимальной стоимости, из которого
+// напрямую извлекаются расписания муравьёв.
+
+// Стоимости рёбер: переход дороже ожидания, поэтому в оптимальном
+// потоке никогда нет встречного обмена двух муравьёв местами.
+const (
+	costMove = 2
+	costWait = 1
+)
+
+// maxExactEdges — предел числа рёбер развёрнутой сети: ребро вместе
+// с обратным занимает около 80 байт, так что одна сеть не превышает
+// ~80 МБ. Время решения растёт так же, как размер сети.
+const maxExactEdges = 1_000_000
+
+// ErrExactTooLarge — развёрнутая во времени сеть для карты не укладывается
+// в maxExactEdges; такую карту решает только быстрый решатель.
+var ErrExactTooLarge = errors.New("map is too large for the exact solver")
+
+type flowEdge struct {
+	to, rev   int
+	cap, orig int
+	cost      int
+}
+
+// flowNet — остаточная сеть для алгоритмов Диница и
+// последовательных кратчайших путей.
+type flowNet struct {
+	adj   [][]flowEdge
+	level []int
+	iter  []int
+}
+
+func newFlowNet(n int) *flowNet {
+	return &flowNet{adj: make([][]flowEdge, n)}
+}
+
+func (f *flowNet) addEdge(from, to, capacity, cost int) {
+	f.adj[from] = append(f.adj[from], flowEdge{to: to, rev: len(f.adj[to]), cap: capacity, orig: capacity, cost: cost})
+	f.adj[to] = append(f.adj[to], flowEdge{to: from, rev: len(f.adj[from]) - 1, cost: -cost})
+}
+
+// maxFlow — алгоритм Диница, останавливается по достижении limit.
+// При отмене ctx (проверяется в каждой фазе и после каждого
+// дополняющего пути) возвращается уже найденный поток.
+func (f *flowNet) maxFlow(ctx context.Context, s, t, limit int) int {
+	flow := 0
+	for flow < limit && ctx.Err() == nil && f.bfsLevels(s, t) {
+		f.iter = make([]int, len(f.adj))
+		for flow < limit && ctx.Err() == nil {
+			pushed := f.dfsPush(s, t, limit-flow)
+			if pushed == 0 {
+				break
+			}
+			flow += pushed
+		}
+	}
+	return flow
+}
+
+func (f *flowNet) bfsLevels(s, t int) bool {
+	f.level = make([]int, len(f.adj))
+	for i := range f.level {
+		f.level[i] = -1
+	}
+	f.level[s] = 0
+	queue := []int{s}
+	for len(queue) > 0 {
+		v := queue[0]
+		queue = queue[1:]
+		for _, e := range f.adj[v] {
+			if e.cap > 0 && f.level[e.to] < 0 {
+				f.level[e.to] = f.level[v] + 1
+				queue = append(queue, e.to)
+			}
+		}
+	}
+	return f.level[t] >= 0
+}
+
+func (f *flowNet) dfsPush(v, t, limit int) int {
+	if v == t {
+		return limit
+	}
+	for ; f.iter[v] < len(f.adj[v]); f.iter[v]++ {
+		e := &f.adj[v][f.iter[v]]
+		if e.cap <= 0 || f.level[e.to] != f.level[v]+1 {
+			continue
+		}
+		if d := f.dfsPush(e.to, t, min(limit, e.cap)); d > 0 {
+			e.cap -= d
+			f.adj[e.to][e.rev].cap += d
+			return d
+		}
+	}
+	return 0
+}
+
+// minCostFlow — последовательные кратчайшие пути (Дейкстра с потенциалами).
+// Все исходные стоимости неотрицательны, поэтому начальные потенциалы нулевые.
+// При отмене ctx возвращается уже найденный поток.
+func (f *flowNet) minCostFlow(ctx context.Context, s, t, limit int) int {
+	n := len(f.adj)
+	potential := make([]int, n)
+	flow := 0
+	for flow < limit && ctx.Err() == nil {
+		dist := make([]int, n)
+		prevNode := make([]int, n)
+		prevEdge := make([]int, n)
+		for i := range dist {
+			dist[i] = -1
+		}
+		dist[s] = 0
+		pq := &distQueue{{node: s}}
+		for pq.Len() > 0 {
+			cur := heap.Pop(pq).(distItem)
+			if cur.dist > dist[cur.node] {
+				continue
+			}
+			for i, e := range f.adj[cur.node] {
+				if e.cap <= 0 {
+					continue
+				}
+				nd := cur.dist + e.cost + potential[cur.node] - potential[e.to]
+				if dist[e.to] < 0 || nd < dist[e.to] {
+					dist[e.to] = nd
+					prevNode[e.to] = cur.node
+					prevEdge[e.to] = i
+					heap.Push(pq, distItem{node: e.to, dist: nd})
+				}
+			}
+		}
+		if dist[t] < 0 {
+			break
+		}
+		for i := range potential {
+			if dist[i] >= 0 {
+				potential[i] += dist[i]
+			}
+		}
+		push := limit - flow
+		for v := t; v != s; v = prevNode[v] {
+			push = min(push, f.adj[prevNode[v]][prevEdge[v]].cap)
+		}
+		for v := t; v != s; v = prevNode[v] {
+			e := &f.adj[prevNode[v]][prevEdge[v]]
+			e.cap -= push
+			f.adj[v][e.rev].cap += push
+		}
+		flow += push
+	}
+	return flow
+}
+
+type distItem struct {
+	node, dist int
+}
+
+type distQueue []distItem
+
+func (q distQueue) Len() int            { return len(q) }
+func (q distQueue) Less(i, j int) bool  { return q[i].dist < q[j].dist }
+func (q distQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *distQueue) Push(x interface{}) { *q = append(*q, x.(distItem)) }
+func (q *distQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
+
+// timeNet описывает развёртку графа на horizon ходов. Вершины
+// (комната, ход) расщеплены на вход и выход, чтобы ограничить вместимость.
+type timeNet struct {
+	g       *Graph
+	names   []string
+	index   map[string]int
+	horizon int
+	net     *flowNet
+}
+
+func (tn *timeNet) in(room, turn int) int  { return 2 * (turn*len(tn.names) + room) }
+func (tn *timeNet) out(room, turn int) int { return tn.in(room, turn) + 1 }
+
+// Исток и сток — две дополнительные вершины после всех слоёв: исток
+// раздаёт каждой колонии её муравьёв, в сток сходятся все финиши.
+func (tn *timeNet) layers() int { return 2 * len(tn.names) * (tn.horizon + 1) }
+func (tn *timeNet) source() int { return tn.layers() }
+func (tn *timeNet) sink() int   { return tn.layers() + 1 }
+
+// timeNetEdges считает (с небольшим запасом) рёбра сети
+// buildTimeNet(g, horizon), не строя её.
+func timeNetEdges(g *Graph, horizon int) int {
+	links := 0
+	for _, next := range g.Links {
+		links += len(next)
+	}
+	perTurn := 2*len(g.Rooms) + links
+	return horizon*perTurn + len(g.Rooms) + len(g.sources()) + len(g.sinks())
+}
+
+// buildTimeNet строит сеть на horizon ходов; отмена ctx проверяется
+// перед каждым слоем.
+func buildTimeNet(ctx context.Context, g *Graph, horizon int) (*timeNet, error) {
+	if edges := timeNetEdges(g, horizon); edges > maxExactEdges {
+		return nil, fmt.Errorf("%w: %d turns need ~%d network edges, limit %d", ErrExactTooLarge, horizon, edges, maxExactEdges)
+	}
+	names := make([]string, 0, len(g.Rooms))
+	for name := range g.Rooms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	tn := &timeNet{g: g, names: names, index: make(map[string]int), horizon: horizon}
+	for i, name := range names {
+		tn.index[name] = i
+	}
+	tn.net = newFlowNet(tn.layers() + 2)
+
+	colonyAnts := make(map[string]int)
+	for _, c := range g.sources() {
+		colonyAnts[c.Start] = c.Ants
+		tn.net.addEdge(tn.source(), tn.in(tn.index[c.Start], 0), c.Ants, 0)
+	}
+	isEnd := make(map[string]bool)
+	for _, end := range g.sinks() {
+		isEnd[end] = true
+		tn.net.addEdge(tn.out(tn.index[end], horizon), tn.sink(), g.NumAnts, 0)
+	}
+
+	for t := 0; t <= horizon; t++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		for r, name := range names {
+			roomCap, waitCost := g.Capacity(name), costWait
+			if g.isTerminal(name) {
+				waitCost = 0
+			}
+			tn.net.addEdge(tn.in(r, t), tn.out(r, t), roomCap, 0)
+			if t == horizon {
+				continue
+			}
+			tn.net.addEdge(tn.out(r, t), tn.in(r, t+1), roomCap, waitCost)
+			if isEnd[name] {
+				continue
+			}
+			for _, next := range g.Links[name] {
+				if _, isStart := colonyAnts[next]; isStart {
+					continue
+				}
+				// Встречные проходы не нужны: замена их ожиданием дешевле,
+				// поэтому достаточно ограничить каждое направление отдельно
+				tunnelCap := g.NumAnts
+				if g.Rules == RulesEdgeCapacity {
+					tunnelCap = 1
+				}
+				if ants, isStart := colonyAnts[name]; isStart && isEnd[next] {
+					tunnelCap = directTunnelCapacity(g.Rules, ants)
+				}
+				tn.net.addEdge(tn.out(r, t), tn.in(tn.index[next], t+1), tunnelCap, costMove)
+			}
+		}
+	}
+	return tn, nil
+}
+
+// shortestDistance возвращает длину кратчайшего пути от старта колонии
+// до ближайшего финиша в рёбрах (BFS) или -1, если финиш недостижим.
+func shortestDistance(g *Graph, start string) int {
+	isEnd := make(map[string]bool)
+	for _, end := range g.sinks() {
+		isEnd[end] = true
+	}
+	dist := map[string]int{start: 0}
+	queue := []string{start}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+		if isEnd[cur] {
+			return dist[cur]
+		}
+		for _, next := range g.Links[cur] {
+			if _, seen := dist[next]; !seen {
+				dist[next] = dist[cur] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return -1
+}
+
+// antTrack — расписание одного муравья: колония (индекс в g.sources())
+// и комната, в которой он находится на каждом ходу.
+type antTrack struct {
+	colony int
+	rooms  []string
+}
+
+// solveExact находит минимальное число ходов бинарным поиском
+// по горизонту сети и возвращает расписания муравьёв в порядке
+// идентификаторов. Горизонт не больше числа ходов быстрого решателя,
+// и сеть на этом горизонте должна укладываться в maxExactEdges, иначе
+// возвращается ErrExactTooLarge. Отмена ctx проверяется при построении
+// сети и внутри потоковых алгоритмов.
+func solveExact(ctx context.Context, g *Graph) ([]antTrack, error) {
+	lo := -1
+	for _, c := range g.sources() {
+		d := shortestDistance(g, c.Start)
+		if d < 0 {
+			return nil, fmt.Errorf("no end room is reachable from start %s", c.Start)
+		}
+		if lo < 0 || d < lo {
+			lo = d
+		}
+	}
+	feasible := func(horizon int) (bool, error) {
+		tn, err := buildTimeNet(ctx, g, horizon)
+		if err != nil {
+			return false, err
+		}
+		flow := tn.net.maxFlow(ctx, tn.source(), tn.sink(), g.NumAnts)
+		return flow >= g.NumAnts, ctx.Err()
+	}
+	limit, err := exactHorizon(g)
+	if err != nil {
+		return nil, err
+	}
+	hi := max(lo, 1)
+	for {
+		ok, err := feasible(hi)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		if hi >= limit {
+			return nil, fmt.Errorf("no feasible flow within %d turns", limit)
+		}
+		lo, hi = hi+1, min(hi*2, limit)
+	}
+	for lo < hi {
+		mid := (lo + hi) / 2
+		ok, err := feasible(mid)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			hi = mid
+		} else {
+			lo = mid + 1
+		}
+	}
+
+	tn, err := buildTimeNet(ctx, g, lo)
+	if err != nil {
+		return nil, err
+	}
+	if tn.net.minCostFlow(ctx, tn.source(), tn.sink(), g.NumAnts) < g.NumAnts {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("no feasible flow within %d turns", lo)
+	}
+	return tn.extractTracks(), nil
+}
+
+// exactHorizon возвращает наибольший горизонт, который проверяет
+// solveExact на карте g (с развёрнутыми туннелями), или ErrExactTooLarge,
+// если сеть на этом горизонте больше maxExactEdges.
+func exactHorizon(g *Graph) (int, error) {
+	// Если вести муравьёв по одному, каждый доходит не более чем
+	// за len(Rooms) ходов, так что решение существует в пределах limit;
+	// решение быстрого решателя тоже допустимо в сети.
+	limit := len(g.Rooms) * (g.NumAnts + 1)
+	if turns, _ := PredictTurns(g); turns > 0 {
+		limit = min(limit, turns)
+	}
+	if edges := timeNetEdges(g, limit); edges > maxExactEdges {
+		return 0, fmt.Errorf("%w: up to %d turns need ~%d network edges, limit %d", ErrExactTooLarge, limit, edges, maxExactEdges)
+	}
+	return limit, nil
+}
+
+// CheckExact заранее, не строя сеть, проверяет, что точный решатель
+// уложится в предел размера сети для карты g при правилах opts.Rules;
+// иначе возвращает ошибку ErrExactTooLarge.
+func CheckExact(ctx context.Context, g *Graph, opts Options) error {
+	g = g.Clone()
+	g.Rules = opts.Rules
+	ex, err := expandWeightedLinks(ctx, g)
+	if err != nil {
+		return err
+	}
+	_, err = exactHorizon(ex)
+	return err
+}
+
+// extractTracks раскладывает поток на единичные пути и переводит каждый
+// в последовательность комнат по ходам. Муравьи упорядочены по колонии,
+// затем по ходу выхода со старта, при равенстве — по имени первой комнаты.
+func (tn *timeNet) extractTracks() []antTrack {
+	colonyOf := make(map[string]int)
+	for i, c := range tn.g.sources() {
+		colonyOf[c.Start] = i
+	}
+	tracks := make([]antTrack, 0, tn.g.NumAnts)
+	for unit := 0; unit < tn.g.NumAnts; unit++ {
+		rooms := make([]string, tn.horizon+1)
+		v := tn.source()
+		for v != tn.sink() {
+			for i := range tn.net.adj[v] {
+				e := &tn.net.adj[v][i]
+				if e.orig > 0 && e.orig-e.cap > 0 {
+					e.cap++
+					v = e.to
+					break
+				}
+			}
+			if v < tn.layers() && v%2 == 1 {
+				pos := v / 2
+				rooms[pos/len(tn.names)] = tn.names[pos%len(tn.names)]
+			}
+		}
+		tracks = append(tracks, antTrack{colony: colonyOf[rooms[0]], rooms: rooms})
+	}
+	departure := func(track antTrack) int {
+		for t, room := range track.rooms {
+			if room != track.rooms[0] {
+				return t
+			}
+		}
+		return len(track.rooms)
+	}
+	sort.SliceStable(tracks, func(i, j int) bool {
+		if tracks[i].colony != tracks[j].colony {
+			return tracks[i].colony < tracks[j].colony
+		}
+		di, dj := departure(tracks[i]), departure(tracks[j])
+		if di != dj {
+			return di < dj
+		}
+		if di >= len(tracks[i].rooms) {
+			return false
+		}
+		return tracks[i].rooms[di] < tracks[j].rooms[dj]
+	})
+	return tracks
+}
+
+// tracksToMoves переводит расписания в строки ходов L<id>-<room>
+// (в режиме колоний — L<колония>.<id>-<room>).
+func tracksToMoves(g *Graph, tracks []antTrack) []string {
+	if len(tracks) == 0 {
+		return nil
+	}
+	labels := trackLabels(g, tracks)
+	var moves []string
+	for t := 1; t < len(tracks[0].rooms); t++ {
+		var step []string
+		for i, track := range tracks {
+			if track.rooms[t] != track.rooms[t-1] {
+				step = append(step, fmt.Sprintf("%s-%s", labels[i], track.rooms[t]))
+			}
+		}
+		if len(step) > 0 {
+			moves = append(moves, strings.Join(step, " "))
+		}
+	}
+	return moves
+}
+
+// trackLabels нумерует муравьёв внутри колоний в порядке расписаний.
+func trackLabels(g *Graph, tracks []antTrack) []string {
+	labels := make([]string, len(tracks))
+	seen := make(map[int]int)
+	for i, track := range tracks {
+		seen[track.colony]++
+		labels[i] = antLabel(g, track.colony, seen[track.colony])
+	}
+	return labels
+}
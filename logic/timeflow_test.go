@@ -0,0 +1,62 @@
+package logic
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestExactMatchesFastSolver подтверждает, что быстрый решатель
+// оптимален на примерах: точный решатель не находит решения короче.
+func TestExactMatchesFastSolver(t *testing.T) {
+	forEachExample(t, func(t *testing.T, _ string, g *Graph) {
+		fast := Solve(context.Background(), g, Options{})
+		if fast.Error != "" {
+			t.Fatal(fast.Error)
+		}
+		ex, err := expandWeightedLinks(context.Background(), g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tracks, err := solveExact(context.Background(), ex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exact := tracksToMoves(ex, tracks)
+		if len(fast.Output) != len(exact) {
+			t.Errorf("fast solver takes %d turns, exact solver %d", len(fast.Output), len(exact))
+		}
+		if err := validateMoves(ex, exact); err != nil {
+			t.Errorf("exact solution is invalid: %v", err)
+		}
+	})
+}
+
+// TestExactTooLarge проверяет, что сеть сверх maxExactEdges не строится.
+func TestExactTooLarge(t *testing.T) {
+	g, _ := chainGraph([]int{1000}, 1000)
+	if _, err := solveExact(context.Background(), g); !errors.Is(err, ErrExactTooLarge) {
+		t.Fatalf("got %v, want ErrExactTooLarge", err)
+	}
+	resp := Solve(context.Background(), g, Options{Exact: true})
+	if !strings.HasPrefix(resp.Error, "ERROR: "+ErrExactTooLarge.Error()) {
+		t.Fatalf("Solve error %q, want the size limit", resp.Error)
+	}
+}
+
+// TestExactCancel проверяет, что срок ctx прерывает одну большую сеть,
+// а не только перебор горизонтов.
+func TestExactCancel(t *testing.T) {
+	g, _ := chainGraph([]int{300}, 300)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := solveExact(ctx, g); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("got %v, want context.DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("cancellation took %v", elapsed)
+	}
+}
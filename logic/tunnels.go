@@ -0,0 +1,107 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// expandWeightedLinks возвращает граф, в котором каждый туннель веса w
+// заменён цепочкой из w-1 промежуточных комнат. Так все решатели,
+// распределение муравьёв и симуляция учитывают вес без отдельной логики: путь
+// становится длиннее ровно на время прохода, а в каждом сегменте
+// туннеля одновременно находится не больше одного муравья.
+// Граф без весов возвращается как есть. При отмене ctx возвращается
+// ctx.Err().
+func expandWeightedLinks(ctx context.Context, g *Graph) (*Graph, error) {
+	if len(g.Weights) == 0 {
+		return g, nil
+	}
+	ex := &Graph{
+		Rooms:    make(map[string]*Room, len(g.Rooms)),
+		Links:    make(map[string][]string, len(g.Links)),
+		Start:    g.Start,
+		End:      g.End,
+		NumAnts:  g.NumAnts,
+		Colonies: g.Colonies,
+		Ends:     g.Ends,
+		Input:    g.Input,
+		Rules:    g.Rules,
+	}
+	for name, room := range g.Rooms {
+		ex.Rooms[name] = &Room{Name: room.Name, X: room.X, Y: room.Y, Capacity: room.Capacity}
+	}
+	// Сегменты двустороннего туннеля получают связи с обеих сторон,
+	// поэтому комнаты обходятся в порядке имён: порядок связей
+	// не должен зависеть от обхода map.
+	froms := make([]string, 0, len(g.Links))
+	for from := range g.Links {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+	for _, from := range froms {
+		for _, to := range g.Links[from] {
+			w := g.Weight(from, to)
+			if w == 1 {
+				ex.Links[from] = append(ex.Links[from], to)
+				continue
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			chain := tunnelRooms(g, from, to, w)
+			ex.Links[from] = append(ex.Links[from], chain[0])
+			for i, name := range chain {
+				if _, ok := ex.Rooms[name]; !ok {
+					a, b := g.Rooms[from], g.Rooms[to]
+					ex.Rooms[name] = &Room{
+						Name:     name,
+						X:        a.X + (b.X-a.X)*(i+1)/w,
+						Y:        a.Y + (b.Y-a.Y)*(i+1)/w,
+						Capacity: 1,
+					}
+				}
+				next := to
+				if i+1 < len(chain) {
+					next = chain[i+1]
+				}
+				ex.Links[name] = append(ex.Links[name], next)
+			}
+		}
+	}
+	return ex, nil
+}
+
+// tunnelRooms возвращает имена промежуточных комнат туннеля from→to
+// в порядке прохода. Двусторонний туннель (связь есть в обе стороны
+// с тем же весом) — одна общая цепочка "a-b:k" (концы упорядочены
+// по имени), односторонний — своя цепочка "from->to:k". Такие имена
+// не пересекаются с настоящими комнатами: имя комнаты не может содержать '-'.
+func tunnelRooms(g *Graph, from, to string, w int) []string {
+	lo, hi, sep := from, to, "-"
+	if !hasLink(g, to, from) || g.Weight(to, from) != w {
+		sep = "->"
+	} else if lo > hi {
+		lo, hi = hi, lo
+	}
+	chain := make([]string, w-1)
+	for k := 1; k < w; k++ {
+		name := fmt.Sprintf("%s%s%s:%d", lo, sep, hi, k)
+		if from == lo {
+			chain[k-1] = name
+		} else {
+			chain[w-1-k] = name
+		}
+	}
+	return chain
+}
+
+// hasLink сообщает, есть ли в графе туннель from→to.
+func hasLink(g *Graph, from, to string) bool {
+	for _, next := range g.Links[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package logic
+
+import (
+	"context"
+	"iter"
+)
+
+// Turn — один ход симуляции в структурированном виде.
+type Turn struct {
+	Number int    `json:"turn"`
+	Moves  []Move `json:"moves"`
+	// Occupancy — число муравьёв в каждой непустой комнате после хода,
+	// включая ещё не вышедших со старта и уже дошедших до финиша.
+	Occupancy map[string]int `json:"occupancy"`
+	Reroutes  []Reroute      `json:"reroutes,omitempty"` // перестроения перед этим ходом
+}
+
+// Move — перемещение одного муравья за ход.
+type Move struct {
+	Ant  string `json:"ant"`
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Simulate решает карту g так же, как Solve, но отдаёт ходы по одному
+// по мере симуляции, не собирая весь вывод в памяти. Ошибка
+// (нет путей, тупик, отмена ctx) отдаётся последним элементом.
+// Граф g не изменяется.
+func Simulate(ctx context.Context, g *Graph, opts Options) iter.Seq2[Turn, error] {
+	return func(yield func(Turn, error) bool) {
+		sol, err := plan(ctx, g, opts)
+		if err != nil {
+			yield(Turn{}, err)
+			return
+		}
+		if sol.tracks != nil {
+			for turn := range trackTurns(sol.g, sol.tracks) {
+				if err := ctx.Err(); err != nil {
+					yield(Turn{}, err)
+					return
+				}
+				if !yield(turn, nil) {
+					return
+				}
+			}
+			return
+		}
+		s := newSimulator(sol.g, sol.fleets, opts.Events)
+		reported := 0
+		for step, err := range s.turns(ctx) {
+			if err != nil {
+				yield(Turn{}, err)
+				return
+			}
+			turn := s.snapshot(step)
+			turn.Reroutes = s.reroutes[reported:]
+			reported = len(s.reroutes)
+			if !yield(turn, nil) {
+				return
+			}
+		}
+	}
+}
+
+// snapshot описывает только что выполненный ход step и занятость комнат.
+func (s *simulator) snapshot(step []move) Turn {
+	turn := Turn{Number: s.turn, Moves: make([]Move, len(step)), Occupancy: make(map[string]int)}
+	for i, m := range step {
+		turn.Moves[i] = Move{Ant: antLabel(s.g, m.fleet, m.id), From: m.from, To: m.room}
+	}
+	for room, n := range s.occupancy {
+		if n > 0 {
+			turn.Occupancy[room] = n
+		}
+	}
+	for room, n := range s.arrived {
+		turn.Occupancy[room] += n
+	}
+	for f, c := range s.g.sources() {
+		if waiting := s.fleets[f].ants - (s.nextID[f] - 1); waiting > 0 {
+			turn.Occupancy[c.Start] += waiting
+		}
+	}
+	return turn
+}
+
+// trackTurns переводит расписания точного решателя в ходы; ходы,
+// в которых все муравьи ждут, пропускаются, как и в tracksToMoves.
+func trackTurns(g *Graph, tracks []antTrack) iter.Seq[Turn] {
+	return func(yield func(Turn) bool) {
+		if len(tracks) == 0 {
+			return
+		}
+		labels := trackLabels(g, tracks)
+		for t := 1; t < len(tracks[0].rooms); t++ {
+			turn := Turn{Number: t, Occupancy: make(map[string]int)}
+			for i, track := range tracks {
+				if track.rooms[t] != track.rooms[t-1] {
+					turn.Moves = append(turn.Moves, Move{Ant: labels[i], From: track.rooms[t-1], To: track.rooms[t]})
+				}
+				turn.Occupancy[track.rooms[t]]++
+			}
+			if len(turn.Moves) > 0 && !yield(turn) {
+				return
+			}
+		}
+	}
+}
@@ -9,30 +9,173 @@ import (
 
 // Response содержит либо последовательность ходов симуляции, либо текст ошибки.
 type Response struct {
-	Error  string
-	Output []string
+	Error    string
+	Output   []string  // строка на каждый ход; при событиях возможны пустые
+	Paths    []PathUse // пути, по которым муравьи выходят со старта
+	Reroutes []Reroute // перестроения путей после событий (Options.Events)
+	Schedule *Schedule // план муравьёв, если запрошен Options.Schedule
+	Explain  []string  // пояснения решателя, если запрошен Options.Explain
 }
 
-// Room описывает вершину графа с координатами и служебными полями
-// для алгоритмов поиска путей (посещённость, родитель, вес, разделение).
+// PathUse — путь решения и число муравьёв, выходящих на него со старта.
+// Для быстрого решателя это распределение до событий, для точного —
+// маршруты муравьёв без ожиданий.
+type PathUse struct {
+	Path Path `json:"path"`
+	Ants int  `json:"ants"`
+}
+
+// Options задаёт режим работы движка.
+type Options struct {
+	// Exact включает точный (медленный) решатель на основе потока
+	// минимальной стоимости в сети, развёрнутой во времени.
+	Exact bool
+	// Rules — правила движения для решателей и симуляции.
+	Rules Rules
+	// Events — закрытия туннелей и комнат во время симуляции.
+	Events []Event
+	// Schedule — вернуть в ответе план движения каждого муравья.
+	// План возвращается, только если его принимает CheckSchedule,
+	// иначе Response.Schedule остаётся nil.
+	Schedule bool
+	// Explain — вернуть в ответе пояснения: выбранную стратегию,
+	// оценённые наборы путей и распределение муравьёв.
+	Explain bool
+	// Order — порядок разрешения равенств между путями (см. Order).
+	Order Order
+}
+
+// Room описывает вершину графа: имя, координаты и вместимость.
+// Решатели хранят своё состояние поиска отдельно, поэтому один граф
+// можно решать из нескольких горутин.
 type Room struct {
-	Name      string
-	X, Y      int
-	Visit     bool  // For Suurballe's algorithm
-	Parent    *Room // For path reconstruction
-	Weight    int   // For path weights
-	Separated bool  // For marking rooms in disjoint paths
+	Name     string
+	X, Y     int
+	Capacity int      // max ants held at once (intermediate rooms only)
+	Tags     []string // free-form metadata from JSON maps; solvers ignore it
 }
 
 // Graph хранит распарсенную конфигурацию муравейника: комнаты, связи,
 // старт/финиш, число муравьёв и исходные строки ввода.
 type Graph struct {
 	Rooms   map[string]*Room    // name -> room
-	Links   map[string][]string // adjacency list
+	Links   map[string][]string // adjacency list (outgoing; "a->b" adds only a -> b)
+	Weights map[[2]string]int   // {from, to} -> turns to traverse, only when != 1
 	Start   string              // name of start room
 	End     string              // name of end room
 	NumAnts int                 // number of ants
 	Input   []string            // raw input lines (trimmed)
+	Rules   Rules               // movement rules used by solvers and simulation
+	// Multi-colony mode ("##start N"); nil for classic maps.
+	Colonies []Colony // colonies in declaration order
+	Ends     []string // all end rooms
+	// Where the parser found each declaration; nil for graphs built in code.
+	Source *Source
+}
+
+// Source — номера строк ввода (с единицы), в которых парсер нашёл
+// объявления. Нужен инструментам, которые сообщают о проблемах карты
+// со ссылкой на строку; решатели его не используют.
+type Source struct {
+	AntsLine int
+	Rooms    map[string]int // name -> line of the room declaration
+	Links    []LinkDecl     // link declarations in input order
+}
+
+// LinkDecl — одно объявление туннеля в исходном вводе.
+type LinkDecl struct {
+	From, To string
+	Directed bool // "a->b"
+	Weight   int  // 1 unless ":w" was given
+	Line     int
+}
+
+// Weight возвращает число ходов, за которое муравей проходит
+// туннель from→to. Туннели без явного веса проходятся за один ход.
+func (g *Graph) Weight(from, to string) int {
+	if w, ok := g.Weights[[2]string{from, to}]; ok {
+		return w
+	}
+	return 1
+}
+
+// Capacity возвращает, сколько муравьёв одновременно вмещает комната.
+// Старт и финиш вмещают всех муравьёв, остальные — по одному,
+// если директива ##capacity не задала иное.
+func (g *Graph) Capacity(name string) int {
+	if g.isTerminal(name) {
+		return g.NumAnts
+	}
+	if room, ok := g.Rooms[name]; ok && room.Capacity > 0 {
+		return room.Capacity
+	}
+	return 1
+}
+
+// Colony — колония в режиме нескольких стартов: стартовая комната
+// и число муравьёв, выходящих из неё.
+type Colony struct {
+	Start string
+	Ants  int
+}
+
+// sources возвращает колонии; классическая карта — одна колония
+// из всех муравьёв на старте.
+func (g *Graph) sources() []Colony {
+	if len(g.Colonies) > 0 {
+		return g.Colonies
+	}
+	return []Colony{{Start: g.Start, Ants: g.NumAnts}}
+}
+
+// sinks возвращает все финишные комнаты.
+func (g *Graph) sinks() []string {
+	if len(g.Ends) > 0 {
+		return g.Ends
+	}
+	return []string{g.End}
+}
+
+// isTerminal сообщает, что комната — старт или финиш (любой колонии).
+func (g *Graph) isTerminal(name string) bool {
+	if name == g.Start || name == g.End {
+		return true
+	}
+	for _, c := range g.Colonies {
+		if c.Start == name {
+			return true
+		}
+	}
+	for _, end := range g.Ends {
+		if end == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone возвращает независимую копию графа: решатели и события
+// меняют связи и служебные поля комнат, а исходный граф остаётся прежним.
+func (g *Graph) Clone() *Graph {
+	c := *g
+	c.Rooms = make(map[string]*Room, len(g.Rooms))
+	for name, room := range g.Rooms {
+		c.Rooms[name] = &Room{Name: room.Name, X: room.X, Y: room.Y, Capacity: room.Capacity, Tags: room.Tags}
+	}
+	c.Links = make(map[string][]string, len(g.Links))
+	for name, links := range g.Links {
+		c.Links[name] = append([]string(nil), links...)
+	}
+	if g.Weights != nil {
+		c.Weights = make(map[[2]string]int, len(g.Weights))
+		for k, w := range g.Weights {
+			c.Weights[k] = w
+		}
+	}
+	c.Input = append([]string(nil), g.Input...)
+	c.Colonies = append([]Colony(nil), g.Colonies...)
+	c.Ends = append([]string(nil), g.Ends...)
+	return &c
 }
 
 // Path — последовательность имён комнат от старта к финишу.
@@ -50,7 +193,8 @@ type sortedQueue struct {
 
 func (q *sortedQueue) Enqueue(room *Room, weight int) {
 	q.items = append(q.items, queueEntry{Room: room, Weight: weight})
-	sort.Slice(q.items, func(i, j int) bool { return q.items[i].Weight < q.items[j].Weight })
+	// Среди равных весов — в порядке добавления, то есть в порядке туннелей
+	sort.SliceStable(q.items, func(i, j int) bool { return q.items[i].Weight < q.items[j].Weight })
 }
 
 func (q *sortedQueue) Dequeue() *queueEntry {
@@ -0,0 +1,87 @@
+package logic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Validate проверяет, что moves (строки ходов L<id>-<room>) — корректное
+// решение для карты input при правилах rules: каждый муравей ходит
+// не больше раза за ход и только по существующему туннелю, вместимость
+// комнат и ограничения туннелей соблюдены, все муравьи дошли до финиша.
+// При первом нарушении возвращается ошибка с номером хода.
+func Validate(input string, moves []string, rules Rules) error {
+	g, err := Parse(input)
+	if err != nil {
+		return fmt.Errorf("invalid map: %v", err)
+	}
+	g.Rules = rules
+	ex, err := expandWeightedLinks(context.Background(), g)
+	if err != nil {
+		return err
+	}
+	return validateMoves(ex, moves)
+}
+
+// validateMoves проверяет ходы на уже подготовленном графе.
+func validateMoves(g *Graph, moves []string) error {
+	pos := make(map[string]string)
+	for c, colony := range g.sources() {
+		for id := 1; id <= colony.Ants; id++ {
+			pos[antLabel(g, c, id)] = colony.Start
+		}
+	}
+	isEnd := make(map[string]bool)
+	for _, end := range g.sinks() {
+		isEnd[end] = true
+	}
+	occupancy := make(map[string]int)
+
+	for t, line := range moves {
+		turn := t + 1
+		moved := make(map[string]bool)
+		crossed := make(map[[2]string]bool)
+		for _, token := range strings.Fields(line) {
+			label, room, ok := strings.Cut(token, "-")
+			if !ok || !strings.HasPrefix(label, "L") {
+				return fmt.Errorf("turn %d: malformed move %q", turn, token)
+			}
+			from, known := pos[label]
+			if !known {
+				return fmt.Errorf("turn %d: unknown ant %s", turn, label)
+			}
+			if moved[label] {
+				return fmt.Errorf("turn %d: ant %s moves twice", turn, label)
+			}
+			if isEnd[from] {
+				return fmt.Errorf("turn %d: ant %s has already finished", turn, label)
+			}
+			if !hasLink(g, from, room) {
+				return fmt.Errorf("turn %d: no tunnel %s-%s for ant %s", turn, from, room, label)
+			}
+			if !g.Rules.allows(crossed, from, room) {
+				return fmt.Errorf("turn %d: tunnel %s-%s used against %s rules by ant %s", turn, from, room, g.Rules, label)
+			}
+			crossed[[2]string{from, room}] = true
+			moved[label] = true
+			pos[label] = room
+			occupancy[from]--
+			occupancy[room]++
+		}
+		for room, n := range occupancy {
+			if !g.isTerminal(room) && n > g.Capacity(room) {
+				return fmt.Errorf("turn %d: room %s holds %d ants, capacity %d", turn, room, n, g.Capacity(room))
+			}
+		}
+	}
+
+	for c, colony := range g.sources() {
+		for id := 1; id <= colony.Ants; id++ {
+			if label := antLabel(g, c, id); !isEnd[pos[label]] {
+				return fmt.Errorf("ant %s did not reach an end (stopped in %s)", label, pos[label])
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"lem-in/logic"
+)
+
+// run — прогон, созданный POST /runs: разобранная карта и параметры
+// решателя. Каждое подключение к потоку событий симулирует его заново,
+// поэтому переподключившийся клиент получает ходы с начала.
+type run struct {
+	g       *logic.Graph
+	opts    logic.Options
+	expires time.Time
+}
+
+// createRunResponse — ответ POST /runs.
+type createRunResponse struct {
+	ID     string `json:"id"`
+	Events string `json:"events"` // адрес потока событий
+}
+
+// doneEvent — последнее событие потока после успешной симуляции.
+type doneEvent struct {
+	Turns int `json:"turns"`
+}
+
+func (s *server) createRun(w http.ResponseWriter, r *http.Request) {
+	_, g, opts, ok := s.readSolveRequest(w, r)
+	if !ok {
+		return
+	}
+	id, err := newRunID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	now := time.Now()
+	s.mu.Lock()
+	for key, old := range s.runs {
+		if now.After(old.expires) {
+			delete(s.runs, key)
+		}
+	}
+	full := len(s.runs) >= s.cfg.MaxRuns
+	if !full {
+		s.runs[id] = &run{g: g, opts: opts, expires: now.Add(s.cfg.RunTTL)}
+	}
+	s.mu.Unlock()
+	if full {
+		writeError(w, http.StatusServiceUnavailable, "too many stored runs, try again later")
+		return
+	}
+	writeJSON(w, http.StatusCreated, createRunResponse{ID: id, Events: "/runs/" + id + "/events"})
+}
+
+// streamRun отдаёт ходы прогона потоком server-sent events: событие
+// "turn" на каждый ход (logic.Turn в JSON), затем "done" с числом
+// ходов или "error". Отключение клиента отменяет контекст запроса,
+// и симуляция останавливается на ближайшем ходу.
+func (s *server) streamRun(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	rn, ok := s.runs[r.PathValue("id")]
+	if ok && time.Now().After(rn.expires) {
+		delete(s.runs, r.PathValue("id"))
+		ok = false
+	}
+	s.mu.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, "run not found")
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.cfg.Timeout)
+	defer cancel()
+	turns := 0
+	for turn, err := range logic.Simulate(ctx, rn.g, rn.opts) {
+		if err != nil {
+			if r.Context().Err() != nil {
+				return // клиент отключился, писать некуда
+			}
+			writeEvent(w, "error", errorResponse{Error: err.Error()})
+			flusher.Flush()
+			return
+		}
+		turns++
+		if err := writeEvent(w, "turn", turn); err != nil {
+			return
+		}
+		flusher.Flush()
+	}
+	writeEvent(w, "done", doneEvent{Turns: turns})
+	flusher.Flush()
+}
+
+// writeEvent записывает одно событие SSE с JSON в поле data.
+func writeEvent(w http.ResponseWriter, name string, v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", name, data)
+	return err
+}
+
+func newRunID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
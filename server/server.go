@@ -0,0 +1,307 @@
+// Package server предоставляет HTTP JSON API над движком lem-in:
+// решение карты, проверку готового решения и проверку живости.
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"lem-in/logic"
+)
+
+// Config задаёт ограничения на запросы и хранимые прогоны.
+type Config struct {
+	// MaxBodyBytes — предельный размер тела запроса; больший запрос
+	// отклоняется с кодом 413.
+	MaxBodyBytes int64
+	// Timeout — сколько времени отводится решателю на один запрос;
+	// по истечении запрос завершается с кодом 504, а поток событий
+	// прогона — событием error.
+	Timeout time.Duration
+	// RunTTL — сколько хранится прогон, созданный POST /runs.
+	RunTTL time.Duration
+	// MaxRuns — предельное число одновременно хранимых прогонов.
+	MaxRuns int
+}
+
+// DefaultConfig — ограничения по умолчанию для "lem-in serve".
+var DefaultConfig = Config{MaxBodyBytes: 1 << 20, Timeout: 10 * time.Second, RunTTL: 10 * time.Minute, MaxRuns: 1000}
+
+// NewHandler возвращает обработчик с маршрутами:
+//
+//	POST /solve             — карта (текст или JSON) → ходы, пути и число ходов
+//	POST /validate          — карта и ходы → отчёт о корректности решения
+//	POST /runs              — карта (как для /solve) → идентификатор прогона
+//	GET  /runs/{id}/events  — ходы прогона потоком server-sent events
+//	GET  /healthz           — проверка живости
+func NewHandler(cfg Config) http.Handler {
+	if cfg.MaxBodyBytes <= 0 {
+		cfg.MaxBodyBytes = DefaultConfig.MaxBodyBytes
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultConfig.Timeout
+	}
+	if cfg.RunTTL <= 0 {
+		cfg.RunTTL = DefaultConfig.RunTTL
+	}
+	if cfg.MaxRuns <= 0 {
+		cfg.MaxRuns = DefaultConfig.MaxRuns
+	}
+	s := &server{cfg: cfg, runs: make(map[string]*run)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /solve", s.solve)
+	mux.HandleFunc("POST /validate", s.validate)
+	mux.HandleFunc("POST /runs", s.createRun)
+	mux.HandleFunc("GET /runs/{id}/events", s.streamRun)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+type server struct {
+	cfg Config
+
+	mu   sync.Mutex
+	runs map[string]*run
+}
+
+// solveRequest — тело POST /solve в формате JSON. Текстовое тело
+// считается картой, а параметры берутся из строки запроса
+// (?rules=edge&exact=true&schedule=true).
+type solveRequest struct {
+	Map      string `json:"map"`
+	Rules    string `json:"rules,omitempty"`    // vertex, no-swap или edge
+	Exact    bool   `json:"exact,omitempty"`    // точный решатель
+	Events   string `json:"events,omitempty"`   // файл событий целиком
+	Schedule bool   `json:"schedule,omitempty"` // вернуть план муравьёв (см. logic.CheckSchedule)
+}
+
+// solveResponse — успешный ответ POST /solve.
+type solveResponse struct {
+	Turns    int             `json:"turns"`
+	Moves    []string        `json:"moves"`
+	Paths    []logic.Path    `json:"paths"`
+	Reroutes []logic.Reroute `json:"reroutes,omitempty"`
+	Schedule *logic.Schedule `json:"schedule,omitempty"`
+}
+
+// validateRequest — тело POST /validate в формате JSON. Текстовое тело
+// принимается в формате вывода lem-in: карта, пустая строка, ходы.
+type validateRequest struct {
+	Map   string   `json:"map"`
+	Moves []string `json:"moves"`
+	Rules string   `json:"rules,omitempty"`
+}
+
+// validateResponse — отчёт POST /validate; некорректное решение —
+// это результат проверки, а не ошибка запроса, поэтому код всегда 200.
+type validateResponse struct {
+	Valid bool   `json:"valid"`
+	Turns int    `json:"turns"`
+	Error string `json:"error,omitempty"`
+}
+
+// errorResponse — тело ответа при ошибке запроса или решателя.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// readSolveRequest разбирает тело запроса на решение и готовит граф
+// и параметры решателя; при ошибке ответ уже отправлен.
+func (s *server) readSolveRequest(w http.ResponseWriter, r *http.Request) (*solveRequest, *logic.Graph, logic.Options, bool) {
+	var req solveRequest
+	if isJSON(r) {
+		if !s.decode(w, r, &req) {
+			return nil, nil, logic.Options{}, false
+		}
+	} else {
+		body, ok := s.readBody(w, r)
+		if !ok {
+			return nil, nil, logic.Options{}, false
+		}
+		q := r.URL.Query()
+		req.Map, req.Rules, req.Events = body, q.Get("rules"), q.Get("events")
+		req.Exact, _ = strconv.ParseBool(q.Get("exact"))
+		req.Schedule, _ = strconv.ParseBool(q.Get("schedule"))
+	}
+
+	rules, err := logic.ParseRules(req.Rules)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return nil, nil, logic.Options{}, false
+	}
+	g, err := logic.Parse(req.Map)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid data format: "+err.Error())
+		return nil, nil, logic.Options{}, false
+	}
+	opts := logic.Options{Exact: req.Exact, Rules: rules}
+	if req.Events != "" {
+		if opts.Events, err = logic.ParseEvents(req.Events); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return nil, nil, logic.Options{}, false
+		}
+	}
+	return &req, g, opts, true
+}
+
+func (s *server) solve(w http.ResponseWriter, r *http.Request) {
+	req, g, opts, ok := s.readSolveRequest(w, r)
+	if !ok {
+		return
+	}
+	if req.Schedule {
+		if err := logic.CheckSchedule(g, opts); err != nil {
+			writeError(w, http.StatusBadRequest, "schedule: "+err.Error())
+			return
+		}
+	}
+	opts.Schedule = req.Schedule
+
+	ctx, cancel := context.WithTimeout(r.Context(), s.cfg.Timeout)
+	defer cancel()
+	// Сеть точного решателя растёт как комнаты × ходы: слишком большие
+	// карты отклоняются до её построения
+	if opts.Exact {
+		if err := logic.CheckExact(ctx, g, opts); errors.Is(err, logic.ErrExactTooLarge) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error())
+			return
+		}
+	}
+	result := logic.Solve(ctx, g, opts)
+	if err := ctx.Err(); err != nil {
+		writeError(w, statusFor(err), "solver stopped: "+err.Error())
+		return
+	}
+	if result.Error != "" {
+		writeError(w, http.StatusUnprocessableEntity, strings.TrimPrefix(result.Error, "ERROR: "))
+		return
+	}
+	resp := solveResponse{
+		Turns:    len(result.Output),
+		Moves:    result.Output,
+		Paths:    make([]logic.Path, len(result.Paths)),
+		Reroutes: result.Reroutes,
+		Schedule: result.Schedule,
+	}
+	for i, use := range result.Paths {
+		resp.Paths[i] = use.Path
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *server) validate(w http.ResponseWriter, r *http.Request) {
+	var req validateRequest
+	if isJSON(r) {
+		if !s.decode(w, r, &req) {
+			return
+		}
+	} else {
+		body, ok := s.readBody(w, r)
+		if !ok {
+			return
+		}
+		req.Map, req.Moves = splitOutput(body)
+		req.Rules = r.URL.Query().Get("rules")
+	}
+
+	rules, err := logic.ParseRules(req.Rules)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if _, err := logic.Parse(req.Map); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid data format: "+err.Error())
+		return
+	}
+	resp := validateResponse{Valid: true, Turns: len(req.Moves)}
+	if err := logic.Validate(req.Map, req.Moves, rules); err != nil {
+		resp.Valid, resp.Error = false, err.Error()
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// splitOutput делит вывод lem-in на карту и строки ходов по первой
+// пустой строке; строки-комментарии среди ходов пропускаются.
+func splitOutput(text string) (string, []string) {
+	lines := strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "" {
+			continue
+		}
+		var moves []string
+		for _, move := range lines[i+1:] {
+			move = strings.TrimSpace(move)
+			if move != "" && !strings.HasPrefix(move, "#") {
+				moves = append(moves, move)
+			}
+		}
+		return strings.Join(lines[:i], "\n"), moves
+	}
+	return text, nil
+}
+
+func isJSON(r *http.Request) bool {
+	return strings.HasPrefix(r.Header.Get("Content-Type"), "application/json")
+}
+
+// readBody читает тело запроса с учётом MaxBodyBytes; при ошибке
+// ответ уже отправлен.
+func (s *server) readBody(w http.ResponseWriter, r *http.Request) (string, bool) {
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes))
+	if err != nil {
+		writeBodyError(w, err)
+		return "", false
+	}
+	return string(data), true
+}
+
+// decode разбирает JSON-тело запроса с учётом MaxBodyBytes; при ошибке
+// ответ уже отправлен.
+func (s *server) decode(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, s.cfg.MaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeBodyError(w, err)
+		return false
+	}
+	return true
+}
+
+func writeBodyError(w http.ResponseWriter, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, "request body exceeds "+strconv.FormatInt(tooLarge.Limit, 10)+" bytes")
+		return
+	}
+	writeError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+}
+
+// statusFor выбирает код ответа для прерванного решателя: 504, если
+// истёк таймаут запроса, и 503, если запрос отменён (клиент закрыл
+// соединение или сервер останавливается) — такой ответ обычно уже
+// никто не прочтёт.
+func statusFor(err error) int {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusServiceUnavailable
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, errorResponse{Error: msg})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
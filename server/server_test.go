@@ -0,0 +1,162 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testMap = `3
+##start
+s 0 1
+##end
+e 3 1
+a 1 2
+b 1 0
+c 2 1
+s-a
+s-b
+a-c
+b-c
+c-e
+`
+
+// post отправляет запрос обработчику h и разбирает JSON-ответ в v.
+func post(t *testing.T, h http.Handler, target, contentType, body string, v any) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	if v != nil {
+		if err := json.Unmarshal(rec.Body.Bytes(), v); err != nil {
+			t.Fatalf("%s: invalid JSON response %q: %v", target, rec.Body.String(), err)
+		}
+	}
+	return rec.Code
+}
+
+func TestSolveText(t *testing.T) {
+	var resp solveResponse
+	code := post(t, NewHandler(DefaultConfig), "/solve?rules=vertex", "text/plain", testMap, &resp)
+	if code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+	if resp.Turns != 5 || len(resp.Moves) != resp.Turns || len(resp.Paths) == 0 {
+		t.Fatalf("got %d turns, %d moves, %d paths; want 5 turns", resp.Turns, len(resp.Moves), len(resp.Paths))
+	}
+}
+
+func TestSolveJSON(t *testing.T) {
+	body, _ := json.Marshal(solveRequest{Map: testMap, Schedule: true})
+	var resp solveResponse
+	code := post(t, NewHandler(DefaultConfig), "/solve", "application/json", string(body), &resp)
+	if code != http.StatusOK {
+		t.Fatalf("status %d, want 200", code)
+	}
+	if resp.Schedule == nil || resp.Schedule.Turns != resp.Turns {
+		t.Fatalf("schedule %+v does not match %d turns", resp.Schedule, resp.Turns)
+	}
+}
+
+func TestSolveErrors(t *testing.T) {
+	h := NewHandler(DefaultConfig)
+	for _, tc := range []struct {
+		name, body string
+		want       int
+	}{
+		{"invalid map", "0\n", http.StatusBadRequest},
+		{"heavy tunnel", "1\n##start\ns 0 0\n##end\ne 1 0\ns-e:1000000000\n", http.StatusBadRequest},
+		{"no paths", "1\n##start\ns 0 0\n##end\ne 1 0\n", http.StatusUnprocessableEntity},
+		{"unknown field", `{"map": "", "extra": 1}`, http.StatusBadRequest},
+		{"schedule with edge rules", `{"map": "1\n##start\ns 0 0\n##end\ne 1 0\ns-e", "rules": "edge", "schedule": true}`, http.StatusBadRequest},
+	} {
+		contentType := "text/plain"
+		if strings.HasPrefix(tc.body, "{") {
+			contentType = "application/json"
+		}
+		var resp errorResponse
+		if code := post(t, h, "/solve", contentType, tc.body, &resp); code != tc.want || resp.Error == "" {
+			t.Errorf("%s: status %d (%q), want %d", tc.name, code, resp.Error, tc.want)
+		}
+	}
+}
+
+func TestSolveBodyTooLarge(t *testing.T) {
+	h := NewHandler(Config{MaxBodyBytes: 16})
+	var resp errorResponse
+	if code := post(t, h, "/solve", "text/plain", testMap, &resp); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("status %d (%q), want 413", code, resp.Error)
+	}
+}
+
+// TestSolveTimeout проверяет, что таймаут прерывает решение уже
+// на развёртывании взвешенных туннелей.
+func TestSolveTimeout(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("10\n##start\ns 0 0\n##end\ne 1 0\n")
+	for i := 0; i < 10; i++ {
+		b.WriteString("s-e:10000\n")
+	}
+	h := NewHandler(Config{Timeout: time.Nanosecond})
+	var resp errorResponse
+	if code := post(t, h, "/solve", "text/plain", b.String(), &resp); code != http.StatusGatewayTimeout {
+		t.Fatalf("status %d (%q), want 504", code, resp.Error)
+	}
+}
+
+// TestSolveExactTooLarge проверяет, что точный решатель не запускается
+// на карте, чья развёрнутая во времени сеть превышает предел.
+func TestSolveExactTooLarge(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("1000\n##start\nr0 0 0\n")
+	for i := 1; i < 999; i++ {
+		fmt.Fprintf(&b, "r%d %d 0\n", i, i)
+	}
+	b.WriteString("##end\nr999 999 0\n")
+	for i := 0; i < 999; i++ {
+		fmt.Fprintf(&b, "r%d-r%d\n", i, i+1)
+	}
+	var resp errorResponse
+	code := post(t, NewHandler(DefaultConfig), "/solve?exact=true", "text/plain", b.String(), &resp)
+	if code != http.StatusUnprocessableEntity || !strings.Contains(resp.Error, "exact solver") {
+		t.Fatalf("status %d (%q), want 422 for the exact solver limit", code, resp.Error)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	h := NewHandler(DefaultConfig)
+	var solved solveResponse
+	post(t, h, "/solve", "text/plain", testMap, &solved)
+
+	var resp validateResponse
+	output := testMap + "\n" + strings.Join(solved.Moves, "\n") + "\n"
+	if code := post(t, h, "/validate", "text/plain", output, &resp); code != http.StatusOK || !resp.Valid {
+		t.Fatalf("status %d, report %+v; want a valid solution", code, resp)
+	}
+
+	body, _ := json.Marshal(validateRequest{Map: testMap, Moves: solved.Moves[:len(solved.Moves)-1]})
+	resp = validateResponse{}
+	if code := post(t, h, "/validate", "application/json", string(body), &resp); code != http.StatusOK || resp.Valid || resp.Error == "" {
+		t.Fatalf("status %d, report %+v; want an invalid solution", code, resp)
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	srv := httptest.NewServer(NewHandler(DefaultConfig))
+	defer srv.Close()
+	res, err := http.Get(srv.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	body, _ := io.ReadAll(res.Body)
+	if res.StatusCode != http.StatusOK || !strings.Contains(string(body), `"ok"`) {
+		t.Fatalf("status %d, body %q", res.StatusCode, body)
+	}
+}